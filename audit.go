@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AuditAction tells what an audit event records.
+type AuditAction string
+
+const (
+	// AuditActionForward is a forward migration run.
+	AuditActionForward AuditAction = "forward"
+	// AuditActionBackward is a backward migration run.
+	AuditActionBackward AuditAction = "backward"
+	// AuditActionForce is a version forced without running migrations.
+	AuditActionForce AuditAction = "force"
+	// AuditActionRefresh is a schema dropped & recreated.
+	AuditActionRefresh AuditAction = "refresh"
+	// AuditActionBaseline is the known migrations recorded as applied up to a version, see Migrate.Baseline.
+	AuditActionBaseline AuditAction = "baseline"
+	// AuditActionRun is a migration run that failed before taking any step,
+	// e.g. on lock timeout, dirty database or checksum mismatch.
+	AuditActionRun AuditAction = "run"
+)
+
+// AuditOutcome tells whether an audited action succeeded.
+type AuditOutcome string
+
+const (
+	// AuditOutcomeSuccess is an action that succeeded.
+	AuditOutcomeSuccess AuditOutcome = "success"
+	// AuditOutcomeFailure is an action that failed, see AuditEvent.Error.
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+// AuditEvent is a row of the append-only audit table, written for every step taken, every schema refreshed
+// and every run failing before its first step.
+// Unlike the migration history, it keeps the trace of rolled back and forced migrations.
+type AuditEvent struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Action     AuditAction `json:"action"`
+	Actor      string      `json:"actor"`
+	AppVersion string      `json:"app_version"`
+
+	// FromVersion and ToVersion are the latest applied migration numbers before and after the action.
+	FromVersion uint `json:"from_version"`
+	ToVersion   uint `json:"to_version"`
+
+	// Number and Name identify the migration, Name is the schema of a refresh.
+	Number uint   `json:"number"`
+	Name   string `json:"name"`
+
+	Outcome  AuditOutcome  `json:"outcome"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// auditStep records the outcome of a step taken from the fromVersion, even when ctx is already cancelled.
+func (m *migrationTask) auditStep(
+	ctx context.Context, step PlanStep, fromVersion, toVersion uint, duration time.Duration, stepErr error,
+) error {
+	//nolint:exhaustivestruct,exhaustruct // ID & created_at are set by the database
+	event := &AuditEvent{
+		Action:      AuditAction(step.Operation),
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Number:      step.Number,
+		Name:        step.Name,
+		Outcome:     AuditOutcomeSuccess,
+		Duration:    duration,
+	}
+
+	if stepErr != nil {
+		event.Outcome = AuditOutcomeFailure
+		event.Error = stepErr.Error()
+	}
+
+	return m.audit(context.WithoutCancel(ctx), event)
+}
+
+// auditRunFailure records the run failing before any step was taken from the fromVersion, even when ctx is
+// already cancelled, and returns runErr along with any error recording it. The audit table is created when
+// missing as the run may fail before ensuring it.
+func (m *migrationTask) auditRunFailure(ctx context.Context, fromVersion uint, runErr error) error {
+	ctx = context.WithoutCancel(ctx)
+
+	if err := m.repo.EnsureAuditTable(ctx); err != nil {
+		return errors.Join(runErr, fmt.Errorf("failed to record %s audit event: %w", AuditActionRun, err))
+	}
+
+	//nolint:exhaustivestruct,exhaustruct // no migration concerned
+	event := &AuditEvent{
+		Action:      AuditActionRun,
+		FromVersion: fromVersion,
+		ToVersion:   fromVersion,
+		Outcome:     AuditOutcomeFailure,
+		Error:       runErr.Error(),
+	}
+
+	return errors.Join(runErr, m.audit(ctx, event))
+}
+
+// audit records the event as taken by the configured actor.
+func (m *migrationTask) audit(ctx context.Context, event *AuditEvent) error {
+	event.Actor = m.opt.AppliedBy
+	event.AppVersion = m.opt.AppVersion
+
+	if err := m.repo.InsertAuditEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s audit event: %w", event.Action, err)
+	}
+
+	return nil
+}
+
+// history returns the audit events in the order they happened.
+func (m *migrationTask) history(ctx context.Context) ([]AuditEvent, error) {
+	events, err := m.repo.GetAuditEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+
+	return events, nil
+}
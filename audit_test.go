@@ -0,0 +1,93 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMigrateAudit(t *testing.T) {
+	t.Parallel()
+
+	someErr := errors.New("test-err") //nolint:goerr113 // used for tests only
+
+	var events []*AuditEvent
+
+	repo := new(mockRepository)
+	repo.On("Lock", mock.Anything, mock.Anything).Return(true, nil)
+	repo.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+	repo.On("DropSchema", mock.Anything, "public").Return(nil)
+	repo.On("EnsureMigrationTable", mock.Anything).Return(nil)
+	repo.On("InsertAuditEvent", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*AuditEvent)) //nolint:forcetypeassert // mocked call
+	})
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(nil, nil).Once()
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionUp).Return(nil).Times(3)
+	err := performMigrateTaskWithMigrations(t, repo,
+		Options{RefreshSchema: true, VersionNumberToApply: 2, AppliedBy: "ci", AppVersion: "v1"})
+	assert.NoError(t, err)
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1, 2), nil).Once()
+	err = performMigrateTaskWithMigrations(t, repo, Options{})
+	assert.NoError(t, err)
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1, 2, 3), nil).Once()
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionDown).Return(nil).Once()
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionDown).Return(someErr).Once()
+	err = performMigrateTaskWithMigrations(t, repo, Options{VersionNumberToApply: 1})
+	assert.ErrorIs(t, err, someErr)
+
+	expected := []AuditEvent{
+		{Action: AuditActionRefresh, Name: "public", Outcome: AuditOutcomeSuccess},
+		{Action: AuditActionForward, FromVersion: 0, ToVersion: 1, Number: 1, Outcome: AuditOutcomeSuccess},
+		{Action: AuditActionForward, FromVersion: 1, ToVersion: 2, Number: 2, Outcome: AuditOutcomeSuccess},
+		{Action: AuditActionForward, FromVersion: 2, ToVersion: 3, Number: 3, Outcome: AuditOutcomeSuccess},
+		{Action: AuditActionBackward, FromVersion: 3, ToVersion: 2, Number: 3, Outcome: AuditOutcomeSuccess},
+		{Action: AuditActionBackward, FromVersion: 2, ToVersion: 2, Number: 2, Outcome: AuditOutcomeFailure},
+	}
+
+	if assert.Len(t, events, len(expected)) {
+		for i := range expected {
+			assert.Equal(t, expected[i].Action, events[i].Action, i)
+			assert.Equal(t, expected[i].FromVersion, events[i].FromVersion, i)
+			assert.Equal(t, expected[i].ToVersion, events[i].ToVersion, i)
+			assert.Equal(t, expected[i].Number, events[i].Number, i)
+			assert.Equal(t, expected[i].Outcome, events[i].Outcome, i)
+		}
+
+		assert.Equal(t, "ci", events[0].Actor)
+		assert.Equal(t, "v1", events[0].AppVersion)
+		assert.Equal(t, "public", events[0].Name)
+		assert.Contains(t, events[5].Error, someErr.Error())
+	}
+}
+
+func TestMigrateAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	someErr := errors.New("test-err")   //nolint:goerr113 // used for tests only
+	auditErr := errors.New("audit-err") //nolint:goerr113 // used for tests only
+
+	repo := new(mockRepository)
+	repo.On("Lock", mock.Anything, mock.Anything).Return(true, nil)
+	repo.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+	repo.On("EnsureMigrationTable", mock.Anything).Return(nil)
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1, 2), nil)
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionUp).Return(someErr).Once()
+	repo.On("InsertAuditEvent", mock.Anything, mock.Anything).Return(auditErr).Once()
+
+	err := performMigrateTaskWithMigrations(t, repo, Options{})
+	assert.ErrorIs(t, err, someErr, "migration error is kept")
+	assert.ErrorIs(t, err, auditErr, "audit error is joined")
+
+	repo.On("GetAuditEvents", mock.Anything).Return(nil, someErr).Once()
+
+	task := migrationTask{migrations: nil, repo: repo, opt: Options{}}
+
+	_, err = task.history(context.Background())
+	assert.ErrorIs(t, err, someErr)
+}
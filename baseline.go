@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errHistoryExists = errors.New("migration history is not empty, baseline an empty history only")
+
+// baseline records every known migration up to version as applied while holding the migration lock.
+// On dry run, it only logs what would be baselined.
+func (m *migrationTask) baseline(ctx context.Context, version uint) error {
+	if m.opt.DryRun {
+		return m.baselineDryRun(ctx, version)
+	}
+
+	return m.withLock(ctx, func(ctx context.Context) error {
+		return m.baselineLocked(ctx, version)
+	})
+}
+
+// baselineDryRun checks the baseline could be taken without writing anything to the database.
+func (m *migrationTask) baselineDryRun(ctx context.Context, version uint) error {
+	exists, err := m.repo.MigrationTableExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check the migrations table: %w", err)
+	}
+
+	var applied []*migration
+
+	if exists {
+		if applied, err = m.repo.GetAppliedMigrations(ctx); err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+	}
+
+	baselined, err := m.baselinedMigrations(applied, version)
+	if err != nil {
+		return err
+	}
+
+	m.opt.Logger.InfoContext(ctx, "dry run: would baseline migrations", LogKeyNumber, version, "count", len(baselined))
+
+	return nil
+}
+
+func (m *migrationTask) baselineLocked(ctx context.Context, version uint) error {
+	if err := m.repo.EnsureMigrationTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	applied, err := m.repo.GetAppliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	baselined, err := m.baselinedMigrations(applied, version)
+	if err != nil {
+		return err
+	}
+
+	m.opt.Logger.InfoContext(ctx, "baselining migrations", LogKeyNumber, version, "count", len(baselined))
+
+	started := time.Now()
+	err = m.repo.BaselineMigrations(ctx, baselined)
+
+	//nolint:exhaustivestruct,exhaustruct // ID, created_at & actor are set on insert
+	event := &AuditEvent{
+		Action:    AuditActionBaseline,
+		ToVersion: version,
+		Number:    version,
+		Name:      baselined[len(baselined)-1].Name,
+		Outcome:   AuditOutcomeSuccess,
+		Duration:  time.Since(started),
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to baseline migrations: %w", err)
+		event.ToVersion, event.Outcome, event.Error = 0, AuditOutcomeFailure, err.Error()
+	}
+
+	if auditErr := m.audit(context.WithoutCancel(ctx), event); auditErr != nil {
+		err = errors.Join(err, auditErr)
+	}
+
+	return err
+}
+
+// baselinedMigrations returns the known migrations up to version to record as applied,
+// failing when some are recorded already or version is not known.
+func (m *migrationTask) baselinedMigrations(applied []*migration, version uint) ([]*migration, error) {
+	if len(applied) > 0 {
+		return nil, fmt.Errorf("%w: %d migrations are recorded", errHistoryExists, len(applied))
+	}
+
+	m.sortMigrationsAsc()
+
+	var baselined []*migration
+
+	for _, migration := range m.migrations {
+		if migration.Number > version {
+			break
+		}
+
+		baselined = append(baselined, migration)
+	}
+
+	if len(baselined) == 0 || baselined[len(baselined)-1].Number != version {
+		return nil, fmt.Errorf("baselining version %d: %w", version, errNoMigrationVersion)
+	}
+
+	return baselined, nil
+}
@@ -0,0 +1,78 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+
+	someErr := errors.New("test-err") //nolint:goerr113 // used for tests only
+
+	repo := new(mockRepository)
+	repo.On("Lock", mock.Anything, mock.Anything).Return(true, nil)
+	repo.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+	repo.On("EnsureMigrationTable", mock.Anything).Return(nil)
+	repo.On("InsertAuditEvent", mock.Anything, mock.Anything).Return(nil)
+
+	task := migrationTask{migrations: mapMigrations(prepareMigrations()), repo: repo, opt: Options{}}
+	task.opt.Logger = newLogger(task.opt)
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(nil, nil).Once()
+	repo.On("BaselineMigrations", mock.Anything, mock.MatchedBy(func(baselined []*migration) bool {
+		return len(baselined) == 2 && baselined[0].Number == 1 && baselined[1].Number == 2
+	})).Return(nil).Once()
+	assert.NoError(t, task.baseline(context.Background(), 2), "Baseline Up To 2")
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1), nil).Once()
+	assert.ErrorIs(t, task.baseline(context.Background(), 2), errHistoryExists, "Existing History")
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(nil, nil).Once()
+	assert.ErrorIs(t, task.baseline(context.Background(), 4), errNoMigrationVersion, "Unknown Version")
+
+	repo.On("GetAppliedMigrations", mock.Anything).Return(nil, nil).Once()
+	repo.On("BaselineMigrations", mock.Anything, mock.Anything).Return(someErr).Once()
+	assert.ErrorIs(t, task.baseline(context.Background(), 3), someErr, "Error On BaselineMigrations")
+
+	repo.AssertExpectations(t)
+
+	// Dry run only reads the history, any write would be an unexpected call.
+	repo = new(mockRepository)
+	task = migrationTask{migrations: mapMigrations(prepareMigrations()), repo: repo, opt: Options{DryRun: true}}
+	task.opt.Logger = newLogger(task.opt)
+
+	repo.On("MigrationTableExists", mock.Anything).Return(false, nil).Once()
+	assert.NoError(t, task.baseline(context.Background(), 2), "Dry Run")
+
+	repo.On("MigrationTableExists", mock.Anything).Return(true, nil).Once()
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1), nil).Once()
+	assert.ErrorIs(t, task.baseline(context.Background(), 2), errHistoryExists, "Dry Run Existing History")
+
+	repo.AssertExpectations(t)
+}
+
+func TestStatusBaselined(t *testing.T) {
+	t.Parallel()
+
+	applied := appliedMigrations(1, 2)
+	applied[0].Baselined = true
+	applied[1].Baselined = true
+
+	repo := new(mockRepository)
+	repo.On("MigrationTableExists", mock.Anything).Return(true, nil).Once()
+	repo.On("GetAppliedMigrations", mock.Anything).Return(applied, nil).Once()
+
+	task := migrationTask{migrations: mapMigrations(prepareMigrations()), repo: repo, opt: Options{}}
+
+	statuses, err := task.status(context.Background())
+	if assert.NoError(t, err) && assert.Len(t, statuses, 3) {
+		assert.True(t, statuses[0].Baselined)
+		assert.Equal(t, StateApplied, statuses[1].State)
+		assert.Equal(t, StatePending, statuses[2].State, "migrations above the baseline stay pending")
+	}
+}
@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ChecksumMismatch describes an applied migration whose checksum differs from the one of the known migration.
+type ChecksumMismatch struct {
+	Number          uint
+	Name            string
+	AppliedChecksum string
+	Checksum        string
+}
+
+// ChecksumMismatchError is returned when applied migrations have been changed since they were applied.
+// Set Options.RepairChecksums to accept the new checksums.
+type ChecksumMismatchError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	migrations := make([]string, len(e.Mismatches))
+	for i, mismatch := range e.Mismatches {
+		migrations[i] = fmt.Sprintf("%d (%s)", mismatch.Number, mismatch.Name)
+	}
+
+	return "applied migrations have been changed since they were applied: " + strings.Join(migrations, ", ")
+}
+
+// sqlChecksum hashes SQL text, ignoring line ending differences.
+func sqlChecksum(query string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(query, "\r\n", "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// findChecksumMismatches compares checksums of the applied migrations against the known ones.
+// Migrations without a checksum on either side are not compared.
+func (m *migrationTask) findChecksumMismatches(applied []*migration) []ChecksumMismatch {
+	knownByNumber := make(map[uint]*migration, len(m.migrations))
+	for _, known := range m.migrations {
+		knownByNumber[known.Number] = known
+	}
+
+	var mismatches []ChecksumMismatch
+
+	for _, appliedMigration := range applied {
+		known, ok := knownByNumber[appliedMigration.Number]
+		if !ok || known.Checksum == "" || appliedMigration.Checksum == known.Checksum {
+			continue
+		}
+
+		if appliedMigration.Checksum == "" && !m.opt.RepairChecksums {
+			continue
+		}
+
+		mismatches = append(mismatches, ChecksumMismatch{
+			Number:          known.Number,
+			Name:            known.Name,
+			AppliedChecksum: appliedMigration.Checksum,
+			Checksum:        known.Checksum,
+		})
+	}
+
+	return mismatches
+}
+
+// validateChecksums fails on changed applied migrations, or records the new checksums with Options.RepairChecksums.
+func (m *migrationTask) validateChecksums(ctx context.Context, applied []*migration) error {
+	mismatches, err := m.getChecksumMismatches(applied)
+	if err != nil {
+		return err
+	}
+
+	for _, mismatch := range mismatches {
+		m.opt.Logger.WarnContext(ctx, "repairing migration checksum",
+			LogKeyNumber, mismatch.Number, LogKeyName, mismatch.Name)
+
+		if err = m.repo.UpdateMigrationChecksum(ctx, mismatch.Number, mismatch.Checksum); err != nil {
+			return fmt.Errorf("failed to repair checksum: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getChecksumMismatches fails on changed applied migrations unless Options.RepairChecksums is set.
+func (m *migrationTask) getChecksumMismatches(applied []*migration) ([]ChecksumMismatch, error) {
+	mismatches := m.findChecksumMismatches(applied)
+	if len(mismatches) > 0 && !m.opt.RepairChecksums {
+		return nil, &ChecksumMismatchError{Mismatches: mismatches}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,267 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+// Exit codes returned by RunCLI & CLI.Run.
+const (
+	ExitCodeOK    = 0
+	ExitCodeError = 1
+	ExitCodeUsage = 2
+)
+
+const defaultCLIName = "migrate"
+
+var errCLIUsage = errors.New("invalid usage")
+
+// CLI is a command line interface over a registry of migrations, with the status, up, down, goto, redo, force,
+// baseline, validate and create commands.
+//
+// Options are applied in this order, each overriding the previous ones: the Options set here, the config file &
+// environment, see LoadOptions, then the flags set. An application embedding CLI should therefore expect
+// e.g. PGMIGRATE_TABLE_NAME to override the TableName it sets. The target to migrate to is always set by the command.
+type CLI struct {
+	// Name is the program name printed in the usage, e.g. "myapp migrate". Defaults to "migrate".
+	Name string
+
+	// Registry holds the migrations to run. Defaults to the default registry, see AddMigration.
+	Registry *Registry
+
+	// Options are the options the flags and commands are applied to.
+	Options Options
+
+	// Dir is the default of the -dir flag: the directory of SQL migrations loaded along with the registry
+	// and the directory create writes to.
+	Dir string
+
+	// LookupEnv looks up the environment variables the Options are loaded from. Defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+}
+
+// RunCLI runs the command line of args, e.g. os.Args[2:] of "myapp migrate status", over the migrations
+// of the default registry, until interrupted. It returns ExitCodeOK on success, ExitCodeError when
+// the command fails and ExitCodeUsage on invalid usage, to be passed to os.Exit.
+func RunCLI(args []string, stdout, stderr io.Writer) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return CLI{}.Run(ctx, args, stdout, stderr)
+}
+
+// cliRun holds the flags and outputs of a single CLI run.
+type cliRun struct {
+	name     string
+	registry *Registry
+	stdout   io.Writer
+	stderr   io.Writer
+
+	dir    string
+	asJSON bool
+	dryRun bool
+	opt    Options
+}
+
+// Run runs the command line of args until ctx is done and returns its exit code, see RunCLI.
+func (c CLI) Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	r := &cliRun{name: c.Name, registry: c.Registry, stdout: stdout, stderr: stderr, opt: c.Options}
+
+	if r.name == "" {
+		r.name = defaultCLIName
+	}
+
+	if r.registry == nil {
+		r.registry = defaultRegistry
+	}
+
+	flags := flag.NewFlagSet(r.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { r.usage(flags) }
+
+	// Flags are parsed apart, as only the ones set override the config file & environment.
+	var (
+		configFile string
+		flagOpt    = c.Options
+	)
+
+	flags.StringVar(&configFile, "config", "",
+		"YAML or TOML config file, "+EnvConfigFile+" by default, see LoadOptions")
+	flags.StringVar(&flagOpt.DatabaseURI, "database-uri", flagOpt.DatabaseURI,
+		"database uri to connect to, the PG* environment variables are used when empty")
+	flags.StringVar(&flagOpt.Driver, "driver", flagOpt.Driver,
+		"database driver, postgres or pgx")
+	flags.StringVar(&r.dir, "dir", c.Dir,
+		"directory of the <number>_<name>.up.sql & .down.sql migration files")
+	flags.StringVar(&flagOpt.TableName, "table", flagOpt.TableName,
+		"name of the migration history table")
+	flags.StringVar(&flagOpt.TableSchema, "schema", flagOpt.TableSchema,
+		"schema of the migration history table")
+	flags.BoolVar(&r.asJSON, "json", false,
+		"print the output, logs and errors as JSON")
+	flags.BoolVar(&r.dryRun, "dry-run", false,
+		"print the steps a command would take without changing the database")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitCodeOK
+		}
+
+		return ExitCodeUsage
+	}
+
+	if flags.NArg() == 0 {
+		r.usage(flags)
+
+		return ExitCodeUsage
+	}
+
+	cmd, ok := cliCommands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", flags.Arg(0))
+		r.usage(flags)
+
+		return ExitCodeUsage
+	}
+
+	lookupEnv := c.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	opt, err := loadOptions(c.Options, configFile, lookupEnv)
+	if err != nil {
+		r.printError(err)
+
+		return ExitCodeError
+	}
+
+	r.opt = withFlagOptions(opt, flagOpt, flags)
+	r.dryRun = r.dryRun || r.opt.DryRun
+
+	if r.opt.Logger == nil {
+		r.opt.Logger = r.logger()
+	}
+
+	if err = cmd.run(ctx, r, flags.Args()[1:]); err != nil {
+		r.printError(err)
+
+		if errors.Is(err, errCLIUsage) {
+			fmt.Fprintf(stderr, "usage: %s [flags] %s\n", r.name, cmd.usage)
+
+			return ExitCodeUsage
+		}
+
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
+
+// withFlagOptions overrides opt with the flags set, and resets its target as the command sets it.
+func withFlagOptions(opt, flagOpt Options, flags *flag.FlagSet) Options {
+	flags.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "database-uri":
+			opt.DatabaseURI = flagOpt.DatabaseURI
+		case "driver":
+			opt.Driver = flagOpt.Driver
+		case "table":
+			opt.TableName = flagOpt.TableName
+		case "schema":
+			opt.TableSchema = flagOpt.TableSchema
+		}
+	})
+
+	opt.VersionNumberToApply, opt.Steps, opt.Redo, opt.RollbackAll = 0, 0, false, false
+	opt.ForceVersionWithoutMigrations = false
+
+	return opt
+}
+
+func (r *cliRun) usage(flags *flag.FlagSet) {
+	fmt.Fprintf(r.stderr, "usage: %s [flags] <command> [arguments]\n\ncommands:\n", r.name)
+
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(r.stderr, "  %-40s %s\n", cliCommands[name].usage, cliCommands[name].summary)
+	}
+
+	fmt.Fprintf(r.stderr, "\nflags:\n")
+	flags.PrintDefaults()
+}
+
+func (r *cliRun) logger() *slog.Logger {
+	if r.asJSON {
+		return slog.New(slog.NewJSONHandler(r.stderr, nil))
+	}
+
+	return slog.New(slog.NewTextHandler(r.stderr, nil))
+}
+
+// print writes value as JSON, or the text lines otherwise.
+func (r *cliRun) print(value any, lines ...string) error {
+	if r.asJSON {
+		encoder := json.NewEncoder(r.stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+
+		return nil
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintln(r.stdout, strings.Join(lines, "\n"))
+	}
+
+	return nil
+}
+
+func (r *cliRun) printError(err error) {
+	if r.asJSON {
+		_ = json.NewEncoder(r.stderr).Encode(map[string]string{"error": err.Error()})
+
+		return
+	}
+
+	fmt.Fprintf(r.stderr, "%s: %v\n", r.name, err)
+}
+
+// newMigrate creates the migration over the registry and the SQL migrations of the directory with opt.
+func (r *cliRun) newMigrate(opt Options) (*Migrate, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	registry := &Registry{migrations: r.registry.Migrations()}
+
+	if r.dir != "" {
+		if err := registry.AddFS(os.DirFS(r.dir), "."); err != nil {
+			return nil, fmt.Errorf("failed to load migrations from %s: %w", r.dir, err)
+		}
+	}
+
+	m, err := NewWithRegistry(registry, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+
+	return m, nil
+}
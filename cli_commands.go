@@ -0,0 +1,345 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type cliCommand struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, r *cliRun, args []string) error
+}
+
+//nolint:gochecknoglobals // command table of the CLI
+var cliCommands = map[string]cliCommand{
+	"status": {
+		usage:   "status",
+		summary: "print the state of every migration",
+		run:     cliStatus,
+	},
+	"up": {
+		usage:   "up [N]",
+		summary: "apply every pending migration, or the next N ones",
+		run:     cliUp,
+	},
+	"down": {
+		usage:   "down [N]",
+		summary: "revert the latest applied migration, or the latest N ones",
+		run:     cliDown,
+	},
+	"goto": {
+		usage:   "goto V",
+		summary: "migrate forward or backward to version V, 0 reverts every migration",
+		run:     cliGoto,
+	},
+	"redo": {
+		usage:   "redo",
+		summary: "revert the latest applied migration and apply it again",
+		run:     cliRedo,
+	},
+	"force": {
+		usage:   "force V",
+		summary: "record version V as the latest applied one without running any migration",
+		run:     cliForce,
+	},
+	"baseline": {
+		usage:   "baseline V",
+		summary: "record every migration up to version V as applied on an unmanaged database",
+		run:     cliBaseline,
+	},
+	"validate": {
+		usage:   "validate",
+		summary: "check the migration files and that the database can be migrated to them",
+		run:     cliValidate,
+	},
+	"create": {
+		usage:   "create [-type sql|go] [-timestamp] NAME",
+		summary: "write the files of a new migration to the directory",
+		run:     cliCreate,
+	},
+}
+
+func cliStatus(ctx context.Context, r *cliRun, args []string) (err error) {
+	if err := cliNoArguments(args); err != nil {
+		return err
+	}
+
+	m, err := r.newMigrate(r.opt)
+	if err != nil {
+		return err
+	}
+
+	defer closeMigrate(m, &err)
+
+	statuses, err := m.StatusContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+
+	if r.asJSON {
+		return r.print(statuses)
+	}
+
+	writer := tabwriter.NewWriter(r.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "NUMBER\tNAME\tSTATE\tAPPLIED AT\tCHECKSUM")
+
+	for _, migrationStatus := range statuses {
+		appliedAt := "-"
+		if migrationStatus.AppliedAt != nil {
+			appliedAt = migrationStatus.AppliedAt.Format(time.DateTime)
+		}
+
+		state := string(migrationStatus.State)
+
+		switch {
+		case migrationStatus.Dirty:
+			state += " (dirty)"
+		case migrationStatus.Baselined:
+			state += " (baselined)"
+		}
+
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\n",
+			migrationStatus.Number, migrationStatus.Name, state, appliedAt, migrationStatus.Checksum)
+	}
+
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("failed to print status: %w", err)
+	}
+
+	return nil
+}
+
+func cliUp(ctx context.Context, r *cliRun, args []string) error {
+	opt := r.opt
+
+	count, err := cliOptionalCount(args)
+	if err != nil {
+		return err
+	}
+
+	opt.Steps = count
+
+	return r.migrate(ctx, opt)
+}
+
+func cliDown(ctx context.Context, r *cliRun, args []string) error {
+	opt := r.opt
+
+	count, err := cliOptionalCount(args)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		count = 1
+	}
+
+	opt.Steps = -count
+
+	return r.migrate(ctx, opt)
+}
+
+func cliGoto(ctx context.Context, r *cliRun, args []string) error {
+	opt := r.opt
+
+	version, err := cliVersionArgument(args)
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		opt.RollbackAll = true
+	} else {
+		opt.VersionNumberToApply = version
+	}
+
+	return r.migrate(ctx, opt)
+}
+
+func cliRedo(ctx context.Context, r *cliRun, args []string) error {
+	if err := cliNoArguments(args); err != nil {
+		return err
+	}
+
+	opt := r.opt
+	opt.Redo = true
+
+	return r.migrate(ctx, opt)
+}
+
+func cliForce(ctx context.Context, r *cliRun, args []string) error {
+	opt := r.opt
+
+	version, err := cliVersionArgument(args)
+	if err != nil {
+		return err
+	}
+
+	opt.VersionNumberToApply = version
+	opt.ForceVersionWithoutMigrations = true
+
+	return r.migrate(ctx, opt)
+}
+
+func cliBaseline(ctx context.Context, r *cliRun, args []string) (err error) {
+	version, err := cliVersionArgument(args)
+	if err != nil {
+		return err
+	}
+
+	// On dry run, Baseline only checks the history could be baselined.
+	opt := r.opt
+	opt.DryRun = r.dryRun
+
+	m, err := r.newMigrate(opt)
+	if err != nil {
+		return err
+	}
+
+	defer closeMigrate(m, &err)
+
+	if err = m.BaselineContext(ctx, version); err != nil {
+		return fmt.Errorf("failed to baseline: %w", err)
+	}
+
+	return r.print(map[string]uint{"baseline": version}, fmt.Sprintf("baseline at version %d", version))
+}
+
+// cliValidate loads the migrations, which fails on malformed or duplicate ones,
+// then plans them against the database, which fails on dirty, changed or out of order migrations.
+func cliValidate(ctx context.Context, r *cliRun, args []string) (err error) {
+	if err := cliNoArguments(args); err != nil {
+		return err
+	}
+
+	m, err := r.newMigrate(r.opt)
+	if err != nil {
+		return err
+	}
+
+	defer closeMigrate(m, &err)
+
+	if _, err = m.PlanContext(ctx); err != nil {
+		return fmt.Errorf("failed to validate migrations: %w", err)
+	}
+
+	return r.print(map[string]bool{"valid": true}, "migrations are valid")
+}
+
+func cliCreate(_ context.Context, r *cliRun, args []string) error {
+	opt := CreateOptions{Dir: r.dir}
+
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.SetOutput(r.stderr)
+	flags.StringVar((*string)(&opt.Type), "type", string(MigrationFileSQL),
+		"type of the migration files, sql or go")
+	flags.BoolVar(&opt.Timestamp, "timestamp", false,
+		"number the migration with the current timestamp instead of the next number")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errCLIUsage, err)
+	}
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("%w: missing migration name", errCLIUsage)
+	}
+
+	if r.dir == "" {
+		return fmt.Errorf("%w: missing -dir to create the migration in", errCLIUsage)
+	}
+
+	opt.Name = strings.Join(flags.Args(), " ")
+
+	paths, err := CreateMigration(opt)
+	if err != nil {
+		return fmt.Errorf("failed to create migration: %w", err)
+	}
+
+	return r.print(paths, paths...)
+}
+
+// migrate applies the migrations with opt, printing the steps taken under the migration lock,
+// or only planned on a dry run.
+func (r *cliRun) migrate(ctx context.Context, opt Options) (err error) {
+	m, err := r.newMigrate(opt)
+	if err != nil {
+		return err
+	}
+
+	defer closeMigrate(m, &err)
+
+	var steps []PlanStep
+
+	if r.dryRun {
+		if steps, err = m.PlanContext(ctx); err != nil {
+			return fmt.Errorf("failed to plan migrations: %w", err)
+		}
+	} else if steps, err = m.task.apply(ctx); err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	lines := make([]string, 0, len(steps))
+	for _, step := range steps {
+		lines = append(lines, step.String())
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, "nothing to apply")
+	}
+
+	return r.print(append([]PlanStep{}, steps...), lines...)
+}
+
+// closeMigrate closes m, joining the error into err.
+func closeMigrate(m *Migrate, err *error) {
+	if closeErr := m.Close(); closeErr != nil {
+		*err = errors.Join(*err, fmt.Errorf("failed to close migrations: %w", closeErr))
+	}
+}
+
+func cliNoArguments(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errCLIUsage, args)
+	}
+
+	return nil
+}
+
+// cliOptionalCount parses the optional positive number of migrations to move.
+func cliOptionalCount(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("%w: %q is not a positive number of migrations", errCLIUsage, args[0])
+		}
+
+		return count, nil
+	default:
+		return 0, fmt.Errorf("%w: unexpected arguments %q", errCLIUsage, args[1:])
+	}
+}
+
+// cliVersionArgument parses the single migration version argument.
+func cliVersionArgument(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected a single version", errCLIUsage)
+	}
+
+	version, err := strconv.ParseUint(args[0], 10, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a migration version", errCLIUsage, args[0])
+	}
+
+	return uint(version), nil
+}
@@ -0,0 +1,160 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCLIUsage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "no command", args: nil, expectedCode: ExitCodeUsage},
+		{name: "help", args: []string{"-h"}, expectedCode: ExitCodeOK},
+		{name: "unknown flag", args: []string{"-unknown", "status"}, expectedCode: ExitCodeUsage},
+		{name: "unknown command", args: []string{"migrate"}, expectedCode: ExitCodeUsage},
+		{name: "invalid count", args: []string{"up", "all"}, expectedCode: ExitCodeUsage},
+		{name: "negative count", args: []string{"down", "-1"}, expectedCode: ExitCodeUsage},
+		{name: "missing version", args: []string{"goto"}, expectedCode: ExitCodeUsage},
+		{name: "invalid version", args: []string{"force", "v1"}, expectedCode: ExitCodeUsage},
+		{name: "unexpected argument", args: []string{"redo", "1"}, expectedCode: ExitCodeUsage},
+		{name: "missing name", args: []string{"create", "-type", "go"}, expectedCode: ExitCodeUsage},
+		{name: "missing directory", args: []string{"-dir", "missing", "validate"}, expectedCode: ExitCodeError},
+	}
+
+	cli := CLI{Registry: NewRegistry(), LookupEnv: lookupTestEnv(nil)}
+
+	for _, testCase := range testCases {
+		var stdout, stderr bytes.Buffer
+
+		code := cli.Run(context.Background(), testCase.args, &stdout, &stderr)
+		assert.Equal(t, testCase.expectedCode, code, testCase.name)
+		assert.Empty(t, stdout.String(), testCase.name)
+
+		if testCase.expectedCode == ExitCodeUsage {
+			assert.Contains(t, stderr.String(), "usage:", testCase.name)
+		}
+	}
+}
+
+func TestCLICreate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cli := CLI{Registry: NewRegistry(), Dir: dir, LookupEnv: lookupTestEnv(nil)}
+
+	var stdout, stderr bytes.Buffer
+
+	code := cli.Run(context.Background(), []string{"-json", "create", "create", "users"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeOK, code, stderr.String())
+
+	var paths []string
+
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &paths))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0001_create_users.up.sql"),
+		filepath.Join(dir, "0001_create_users.down.sql"),
+	}, paths)
+
+	stdout.Reset()
+
+	code = cli.Run(context.Background(), []string{"create", "-timestamp", "add phone"}, &stdout, &stderr)
+	if strconv.IntSize < 64 {
+		assert.Equal(t, ExitCodeError, code, "timestamp versions overflow a 32-bit uint")
+
+		return
+	}
+
+	assert.Equal(t, ExitCodeOK, code, stderr.String())
+	assert.Regexp(t, `^.*/\d{14}_add_phone\.up\.sql\n.*/\d{14}_add_phone\.down\.sql\n$`, stdout.String())
+}
+
+func TestCLIValidate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0001_create_users.up.sql"), []byte("SELECT 1"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "1_create_users.up.sql"), []byte("SELECT 1"), 0o600))
+
+	var stdout, stderr bytes.Buffer
+
+	cli := CLI{Registry: NewRegistry(), LookupEnv: lookupTestEnv(nil)}
+
+	code := cli.Run(context.Background(), []string{"-dir", dir, "-json", "validate"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Empty(t, stdout.String())
+
+	var output map[string]string
+
+	assert.NoError(t, json.Unmarshal(stderr.Bytes(), &output))
+	assert.Contains(t, output["error"], "duplicate migration file")
+}
+
+func TestCLIRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := prepareRegistry(prepareMigrations()[0])
+
+	var stdout, stderr bytes.Buffer
+
+	cli := CLI{Name: "myapp migrate", Registry: registry, LookupEnv: lookupTestEnv(nil)}
+
+	code := cli.Run(context.Background(), []string{"validate"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Contains(t, stderr.String(), "myapp migrate: failed to initialize migrations")
+	assert.Contains(t, stderr.String(), "duplicate numbers")
+
+	stderr.Reset()
+
+	code = cli.Run(context.Background(), []string{"create", "add phone"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeUsage, code, "create without a directory")
+	assert.Contains(t, stderr.String(), "usage: myapp migrate [flags] create")
+}
+
+func TestCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	configFile := writeConfigFile(t, "migrate.yaml", "driver: mysql\nversion: 2\nforce_version: true\n")
+	cli := CLI{Registry: NewRegistry(), LookupEnv: lookupTestEnv(nil)}
+
+	var stdout, stderr bytes.Buffer
+
+	code := cli.Run(context.Background(), []string{"-config", configFile, "redo"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Contains(t, stderr.String(), "unknown driver", "config file loaded")
+	assert.NotContains(t, stderr.String(), "conflicting", "target set by the command")
+
+	stderr.Reset()
+
+	code = cli.Run(context.Background(), []string{"-config", configFile, "-driver", "pgx", "-dir", "missing", "redo"},
+		&stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Contains(t, stderr.String(), "failed to load migrations", "flag over config file")
+
+	stderr.Reset()
+
+	code = cli.Run(context.Background(), []string{"-config", "migrate.ini", "status"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Contains(t, stderr.String(), errUnknownConfigFormat.Error())
+
+	stderr.Reset()
+
+	cli.Options.Driver = DriverPgx
+	cli.LookupEnv = lookupTestEnv(map[string]string{EnvPrefix + "DRIVER": "mysql"})
+
+	code = cli.Run(context.Background(), []string{"redo"}, &stdout, &stderr)
+	assert.Equal(t, ExitCodeError, code)
+	assert.Contains(t, stderr.String(), "unknown driver", "environment over code-set options")
+}
@@ -0,0 +1,31 @@
+// Command pgmigrate applies the SQL migrations of a directory to a PostgreSQL database.
+//
+// Usage:
+//
+//	pgmigrate [flags] <command> [arguments]
+//
+// The database is read from -database-uri, or from the standard libpq PG* environment variables
+// (PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE, PGSSLMODE, ...) when it is not set.
+// The other options are read from the PGMIGRATE_* environment variables and the -config file,
+// which the flags set override.
+//
+// Exit codes are 0 on success, 1 when the command fails and 2 on invalid usage.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	migrate "github.com/lawzava/go-pg-migrate/v2"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	cli := migrate.CLI{Name: "pgmigrate", Registry: migrate.NewRegistry(), Dir: "migrations"}
+	code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
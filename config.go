@@ -0,0 +1,355 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables read by LoadOptions, e.g. PGMIGRATE_DATABASE_URI.
+const EnvPrefix = "PGMIGRATE_"
+
+// EnvConfigFile names the config file LoadOptions reads when none is given.
+const EnvConfigFile = EnvPrefix + "CONFIG"
+
+var (
+	errUnknownConfigFormat  = errors.New("unknown config file format, expected .yaml, .yml or .toml")
+	errUnknownConfigSetting = errors.New("unknown config setting")
+	errInvalidConfigValue   = errors.New("invalid config value")
+	errConflictingSettings  = errors.New("conflicting settings")
+)
+
+// configSetting is an Options field settable by its key in a config file,
+// or by the upper case key with EnvPrefix in the environment.
+type configSetting struct {
+	key string
+	set func(opt *Options, value string) error
+}
+
+//nolint:gochecknoglobals // settings table shared by the config file & environment variables
+var configSettings = []configSetting{
+	{"database_uri", stringSetting(func(opt *Options) *string { return &opt.DatabaseURI })},
+	{"driver", stringSetting(func(opt *Options) *string { return &opt.Driver })},
+	{"version", uintSetting(func(opt *Options) *uint { return &opt.VersionNumberToApply })},
+	{"steps", intSetting(func(opt *Options) *int { return &opt.Steps })},
+	{"redo", boolSetting(func(opt *Options) *bool { return &opt.Redo })},
+	{"rollback_all", boolSetting(func(opt *Options) *bool { return &opt.RollbackAll })},
+	{"force_version", boolSetting(func(opt *Options) *bool { return &opt.ForceVersionWithoutMigrations })},
+	{"refresh_schema", boolSetting(func(opt *Options) *bool { return &opt.RefreshSchema })},
+	{"schemas_to_refresh", listSetting(func(opt *Options) *[]string { return &opt.SchemasToRefresh })},
+	{"table_name", stringSetting(func(opt *Options) *string { return &opt.TableName })},
+	{"table_schema", stringSetting(func(opt *Options) *string { return &opt.TableSchema })},
+	{"move_legacy_table", boolSetting(func(opt *Options) *bool { return &opt.MoveLegacyTable })},
+	{"clear_dirty", boolSetting(func(opt *Options) *bool { return &opt.ClearDirty })},
+	{"allow_out_of_order", boolSetting(func(opt *Options) *bool { return &opt.AllowOutOfOrder })},
+	{"repair_checksums", boolSetting(func(opt *Options) *bool { return &opt.RepairChecksums })},
+	{"applied_by", stringSetting(func(opt *Options) *string { return &opt.AppliedBy })},
+	{"app_version", stringSetting(func(opt *Options) *string { return &opt.AppVersion })},
+	{"dry_run", boolSetting(func(opt *Options) *bool { return &opt.DryRun })},
+	{"lock_key", int64Setting(func(opt *Options) *int64 { return &opt.LockKey })},
+	{"lock_timeout", durationSetting(func(opt *Options) *time.Duration { return &opt.LockTimeout })},
+}
+
+// LoadOptions builds Options from a YAML or TOML config file and the environment, highest precedence first:
+//
+//  1. PGMIGRATE_* environment variables, e.g. PGMIGRATE_TABLE_NAME.
+//  2. The settings of configFile, e.g. table_name, or of the PGMIGRATE_CONFIG file when configFile is empty.
+//     Without either, no file is read.
+//  3. The libpq PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE & PGSSLMODE variables, only used to build
+//     DatabaseURI when neither of the above sets it.
+//
+// Lists such as PGMIGRATE_SCHEMAS_TO_REFRESH are comma separated in the environment.
+// Run Options.Validate on the result to check for conflicting settings.
+func LoadOptions(configFile string) (Options, error) {
+	return loadOptions(Options{}, configFile, os.LookupEnv)
+}
+
+// loadOptions applies the config file and the environment looked up with lookupEnv to opt, see LoadOptions.
+func loadOptions(opt Options, configFile string, lookupEnv func(key string) (string, bool)) (Options, error) {
+	if configFile == "" {
+		configFile, _ = lookupEnv(EnvConfigFile)
+	}
+
+	if configFile != "" {
+		settings, err := readConfigFile(configFile)
+		if err != nil {
+			return Options{}, err
+		}
+
+		for _, setting := range configSettings {
+			if value, ok := settings[setting.key]; ok {
+				if err = applySetting(&opt, setting, value, configFile); err != nil {
+					return Options{}, err
+				}
+			}
+
+			delete(settings, setting.key)
+		}
+
+		if len(settings) > 0 {
+			return Options{}, fmt.Errorf("%s: %w: %s",
+				configFile, errUnknownConfigSetting, strings.Join(sortedKeys(settings), ", "))
+		}
+	}
+
+	for _, setting := range configSettings {
+		key := EnvPrefix + strings.ToUpper(setting.key)
+
+		if value, ok := lookupEnv(key); ok {
+			if err := applySetting(&opt, setting, value, key); err != nil {
+				return Options{}, err
+			}
+		}
+	}
+
+	if opt.DatabaseURI == "" && opt.DB == nil && opt.Connector == nil && opt.PgxPool == nil {
+		opt.DatabaseURI = libpqEnvURI(lookupEnv)
+	}
+
+	return opt, nil
+}
+
+func applySetting(opt *Options, setting configSetting, value, source string) error {
+	if err := setting.set(opt, value); err != nil {
+		return fmt.Errorf("%s: %w for %s: %q", source, errInvalidConfigValue, setting.key, value)
+	}
+
+	return nil
+}
+
+// readConfigFile reads the settings of a YAML or TOML file, lists joined with commas as in the environment.
+func readConfigFile(configFile string) (map[string]string, error) {
+	var decode func(content []byte, values map[string]any) error
+
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yaml", ".yml":
+		decode = func(content []byte, values map[string]any) error {
+			err := yaml.NewDecoder(bytes.NewReader(content)).Decode(&values)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err //nolint:wrapcheck // wrapped with the file name below
+		}
+	case ".toml":
+		decode = func(content []byte, values map[string]any) error {
+			_, err := toml.NewDecoder(bytes.NewReader(content)).Decode(&values)
+
+			return err //nolint:wrapcheck // wrapped with the file name below
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", configFile, errUnknownConfigFormat)
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+
+	if err = decode(content, values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+	}
+
+	settings := make(map[string]string, len(values))
+
+	for key, value := range values {
+		switch typed := value.(type) {
+		case []any:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+
+			settings[key] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: %w for %s: nested settings", configFile, errInvalidConfigValue, key)
+		default:
+			settings[key] = fmt.Sprint(typed)
+		}
+	}
+
+	return settings, nil
+}
+
+// libpqEnvURI builds a connection string from the libpq environment variables, empty when none is set.
+func libpqEnvURI(lookupEnv func(key string) (string, bool)) string {
+	env := func(key string) string {
+		value, _ := lookupEnv(key)
+
+		return value
+	}
+
+	host, port, user, password, database, sslMode := env("PGHOST"), env("PGPORT"), env("PGUSER"),
+		env("PGPASSWORD"), env("PGDATABASE"), env("PGSSLMODE")
+
+	if host+port+user+password+database+sslMode == "" {
+		return ""
+	}
+
+	uri := url.URL{Scheme: "postgres", Path: "/" + database}
+	query := url.Values{}
+
+	// A host starting with a slash is the directory of a Unix socket, which only fits in the query.
+	if strings.HasPrefix(host, "/") {
+		query.Set("host", host)
+		host = ""
+	}
+
+	uri.Host = host
+	if port != "" {
+		uri.Host += ":" + port
+	}
+
+	switch {
+	case password != "":
+		uri.User = url.UserPassword(user, password)
+	case user != "":
+		uri.User = url.User(user)
+	}
+
+	if sslMode != "" {
+		query.Set("sslmode", sslMode)
+	}
+
+	uri.RawQuery = query.Encode()
+
+	return uri.String()
+}
+
+// Validate reports the conflicting settings of the options, e.g. RefreshSchema with ForceVersionWithoutMigrations,
+// along with the other invalid settings New would refuse.
+func (opt Options) Validate() error {
+	var errs []error
+
+	if err := validateTarget(opt); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateDatabase(opt); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := sqlDriverName(opt.Driver); err != nil {
+		errs = append(errs, err)
+	}
+
+	if opt.ForceVersionWithoutMigrations {
+		if opt.RefreshSchema || len(opt.SchemasToRefresh) > 0 {
+			errs = append(errs, fmt.Errorf("%w: ForceVersionWithoutMigrations would record a version "+
+				"on a refreshed schema that has none of the migrations applied", errConflictingSettings))
+		}
+
+		if opt.Steps != 0 || opt.Redo || opt.RollbackAll {
+			errs = append(errs, fmt.Errorf("%w: ForceVersionWithoutMigrations only applies to VersionNumberToApply",
+				errConflictingSettings))
+		}
+
+		if opt.VersionNumberToApply == 0 {
+			errs = append(errs, fmt.Errorf("ForceVersionWithoutMigrations requires VersionNumberToApply: %w",
+				errNoMigrationVersion))
+		}
+	}
+
+	if opt.PrintInfoAndExit && (opt.RefreshSchema || len(opt.SchemasToRefresh) > 0) {
+		errs = append(errs, fmt.Errorf("%w: PrintInfoAndExit would refresh the schema before printing",
+			errConflictingSettings))
+	}
+
+	if opt.LockTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%w for LockTimeout: %s", errInvalidConfigValue, opt.LockTimeout))
+	}
+
+	return errors.Join(errs...)
+}
+
+func stringSetting(field func(opt *Options) *string) func(opt *Options, value string) error {
+	return func(opt *Options, value string) error {
+		*field(opt) = value
+
+		return nil
+	}
+}
+
+// parsedSetting sets the field to the value parsed with parse,
+// the parse error is wrapped with the setting key by applySetting.
+func parsedSetting[T any](
+	field func(opt *Options) *T, parse func(value string) (T, error),
+) func(opt *Options, value string) error {
+	return func(opt *Options, value string) error {
+		parsed, err := parse(value)
+		if err != nil {
+			return err
+		}
+
+		*field(opt) = parsed
+
+		return nil
+	}
+}
+
+func boolSetting(field func(opt *Options) *bool) func(opt *Options, value string) error {
+	return parsedSetting(field, strconv.ParseBool)
+}
+
+func intSetting(field func(opt *Options) *int) func(opt *Options, value string) error {
+	return parsedSetting(field, strconv.Atoi)
+}
+
+func uintSetting(field func(opt *Options) *uint) func(opt *Options, value string) error {
+	return parsedSetting(field, func(value string) (uint, error) {
+		parsed, err := strconv.ParseUint(value, 10, strconv.IntSize)
+
+		return uint(parsed), err
+	})
+}
+
+func int64Setting(field func(opt *Options) *int64) func(opt *Options, value string) error {
+	return parsedSetting(field, func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	})
+}
+
+func durationSetting(field func(opt *Options) *time.Duration) func(opt *Options, value string) error {
+	return parsedSetting(field, time.ParseDuration)
+}
+
+// listSetting splits a comma separated list, as lists are written in the environment.
+func listSetting(field func(opt *Options) *[]string) func(opt *Options, value string) error {
+	return func(opt *Options, value string) error {
+		var items []string
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		*field(opt) = items
+
+		return nil
+	}
+}
+
+func sortedKeys(settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,226 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupTestEnv(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile
+}
+
+func TestLoadOptions(t *testing.T) {
+	t.Parallel()
+
+	yamlFile := writeConfigFile(t, "migrate.yaml", `
+database_uri: postgres://file@localhost:5432/app
+table_name: schema_migrations
+schemas_to_refresh: [public, test]
+allow_out_of_order: true
+lock_timeout: 30s
+lock_key: 42
+`)
+
+	opt, err := loadOptions(Options{AppVersion: "code"}, yamlFile, lookupTestEnv(map[string]string{
+		"PGMIGRATE_TABLE_NAME": "env_migrations",
+		"PGMIGRATE_STEPS":      "-2",
+		"PGHOST":               "ignored",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, Options{
+		DatabaseURI:      "postgres://file@localhost:5432/app",
+		Steps:            -2,
+		SchemasToRefresh: []string{"public", "test"},
+		TableName:        "env_migrations",
+		AllowOutOfOrder:  true,
+		AppVersion:       "code",
+		LockKey:          42,
+		LockTimeout:      30 * time.Second,
+	}, opt, "environment over file over code")
+
+	tomlFile := writeConfigFile(t, "migrate.toml", `
+table_schema = "history"
+refresh_schema = true
+schemas_to_refresh = ["public"]
+version = 3
+`)
+
+	opt, err = loadOptions(Options{}, "", lookupTestEnv(map[string]string{
+		"PGMIGRATE_CONFIG":             tomlFile,
+		"PGMIGRATE_SCHEMAS_TO_REFRESH": "public, audit",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, Options{
+		VersionNumberToApply: 3,
+		RefreshSchema:        true,
+		SchemasToRefresh:     []string{"public", "audit"},
+		TableSchema:          "history",
+	}, opt, "config file from the environment")
+
+	opt, err = loadOptions(Options{}, writeConfigFile(t, "empty.yml", ""), lookupTestEnv(nil))
+	assert.NoError(t, err, "empty config file")
+	assert.Equal(t, Options{}, opt)
+}
+
+func TestLoadOptionsDatabaseURI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		opt         Options
+		env         map[string]string
+		expectedURI string
+	}{
+		{
+			name:        "no variables",
+			expectedURI: "",
+		},
+		{
+			name: "libpq variables",
+			env: map[string]string{
+				"PGHOST": "db", "PGPORT": "6432", "PGUSER": "app", "PGPASSWORD": "p@ss", "PGDATABASE": "app",
+				"PGSSLMODE": "disable",
+			},
+			expectedURI: "postgres://app:p%40ss@db:6432/app?sslmode=disable",
+		},
+		{
+			name:        "unix socket",
+			env:         map[string]string{"PGHOST": "/var/run/postgresql", "PGUSER": "app"},
+			expectedURI: "postgres://app@/?host=%2Fvar%2Frun%2Fpostgresql",
+		},
+		{
+			name:        "database uri over libpq variables",
+			env:         map[string]string{"PGMIGRATE_DATABASE_URI": "postgres://env/app", "PGHOST": "db"},
+			expectedURI: "postgres://env/app",
+		},
+		{
+			name:        "database from code",
+			opt:         Options{DB: &sql.DB{}},
+			env:         map[string]string{"PGHOST": "db"},
+			expectedURI: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		opt, err := loadOptions(testCase.opt, "", lookupTestEnv(testCase.env))
+		assert.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.expectedURI, opt.DatabaseURI, testCase.name)
+	}
+}
+
+func TestLoadOptionsErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		configFile  string
+		env         map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "missing file",
+			configFile:  filepath.Join(t.TempDir(), "missing.yaml"),
+			expectedErr: os.ErrNotExist,
+		},
+		{
+			name:        "unknown format",
+			configFile:  writeConfigFile(t, "migrate.json", "{}"),
+			expectedErr: errUnknownConfigFormat,
+		},
+		{
+			name:        "unknown setting",
+			configFile:  writeConfigFile(t, "migrate.yaml", "table: migrations"),
+			expectedErr: errUnknownConfigSetting,
+		},
+		{
+			name:        "nested setting",
+			configFile:  writeConfigFile(t, "migrate.toml", "[database]\nuri = \"postgres://localhost\""),
+			expectedErr: errInvalidConfigValue,
+		},
+		{
+			name:        "invalid file value",
+			configFile:  writeConfigFile(t, "migrate.yml", "lock_timeout: 10"),
+			expectedErr: errInvalidConfigValue,
+		},
+		{
+			name:        "invalid environment value",
+			env:         map[string]string{"PGMIGRATE_REDO": "sure"},
+			expectedErr: errInvalidConfigValue,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := loadOptions(Options{}, testCase.configFile, lookupTestEnv(testCase.env))
+		assert.ErrorIs(t, err, testCase.expectedErr, testCase.name)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		opt          Options
+		expectedErrs []error
+	}{
+		{
+			name: "valid",
+			opt:  Options{DatabaseURI: "postgres://localhost", VersionNumberToApply: 2, ForceVersionWithoutMigrations: true},
+		},
+		{
+			name:         "refresh schema with force version",
+			opt:          Options{RefreshSchema: true, VersionNumberToApply: 2, ForceVersionWithoutMigrations: true},
+			expectedErrs: []error{errConflictingSettings},
+		},
+		{
+			name:         "force version without version",
+			opt:          Options{ForceVersionWithoutMigrations: true, Redo: true},
+			expectedErrs: []error{errConflictingSettings, errNoMigrationVersion},
+		},
+		{
+			name:         "print info with refreshed schemas",
+			opt:          Options{PrintInfoAndExit: true, SchemasToRefresh: []string{"public"}},
+			expectedErrs: []error{errConflictingSettings},
+		},
+		{
+			name: "every invalid setting",
+			opt: Options{
+				DatabaseURI: "postgres://localhost", DB: &sql.DB{}, Driver: "mysql", Steps: 1, Redo: true,
+				LockTimeout: -time.Second,
+			},
+			expectedErrs: []error{errConflictingTargets, errMultipleDatabases, errUnknownDriver, errInvalidConfigValue},
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.opt.Validate()
+
+		if len(testCase.expectedErrs) == 0 {
+			assert.NoError(t, err, testCase.name)
+		}
+
+		for _, expectedErr := range testCase.expectedErrs {
+			assert.ErrorIs(t, err, expectedErr, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errDirtyMigrations = errors.New(
+	"database is dirty, set ClearDirty once the partially applied changes are dealt with")
+
+// dirtyMigrations returns the migrations recorded as dirty, failing on them unless Options.ClearDirty is set.
+func (m *migrationTask) dirtyMigrations(applied []*migration) ([]*migration, error) {
+	var (
+		dirty       []*migration
+		description []string
+	)
+
+	for _, appliedMigration := range applied {
+		if appliedMigration.Dirty {
+			dirty = append(dirty, appliedMigration)
+			description = append(description, fmt.Sprintf("%d (%s)", appliedMigration.Number, appliedMigration.Name))
+		}
+	}
+
+	if len(dirty) > 0 && !m.opt.ClearDirty {
+		return nil, fmt.Errorf("%w: migrations %s failed without a transaction",
+			errDirtyMigrations, strings.Join(description, ", "))
+	}
+
+	return dirty, nil
+}
+
+// withoutDirtyMigrations returns the applied migrations not recorded as dirty.
+func withoutDirtyMigrations(applied []*migration) []*migration {
+	clean := make([]*migration, 0, len(applied))
+
+	for _, appliedMigration := range applied {
+		if !appliedMigration.Dirty {
+			clean = append(clean, appliedMigration)
+		}
+	}
+
+	return clean
+}
+
+// handleDirtyMigrations refuses to proceed on dirty migrations, or removes their records with Options.ClearDirty.
+func (m *migrationTask) handleDirtyMigrations(ctx context.Context, applied []*migration) ([]*migration, error) {
+	dirty, err := m.dirtyMigrations(applied)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dirty) == 0 {
+		return applied, nil
+	}
+
+	for _, dirtyMigration := range dirty {
+		m.opt.Logger.WarnContext(ctx, "clearing dirty migration",
+			LogKeyNumber, dirtyMigration.Number, LogKeyName, dirtyMigration.Name)
+	}
+
+	if err = m.repo.RemoveDirtyMigrations(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clear dirty migrations: %w", err)
+	}
+
+	return withoutDirtyMigrations(applied), nil
+}
@@ -17,7 +17,7 @@ func init() {
 			Name:   "Add Address For Users",
 			Number: 3,
 			Up: func(tx migrate.Tx) error {
-				_, err := tx.Exec(up)
+				_, err := tx.ExecContext(tx.Context(), up)
 				if err != nil {
 					return fmt.Errorf("failed to alter users table to add address: %w", err)
 				}
@@ -25,7 +25,7 @@ func init() {
 				return nil
 			},
 			Down: func(tx migrate.Tx) error {
-				_, err := tx.Exec(down)
+				_, err := tx.ExecContext(tx.Context(), down)
 				if err != nil {
 					return fmt.Errorf("failed to drop address column for users table: %w", err)
 				}
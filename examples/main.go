@@ -1,34 +1,21 @@
 package main
 
 import (
-	"flag"
+	"embed"
 	"log"
+	"os"
 
 	migrate "github.com/lawzava/go-pg-migrate/v2"
 )
 
-func main() {
-	var opt migrate.Options
-
-	flag.StringVar(&opt.DatabaseURI, "database-uri", "postgres://postgres@localhost:5432/migrate-test",
-		"database uri to connect to")
-	flag.UintVar(&opt.VersionNumberToApply, "version", 0,
-		"migrate to specified printVersion; 0 will apply all forward migrations")
-	flag.BoolVar(&opt.PrintInfoAndExit, "current", false,
-		"print last applied version and exit")
-	flag.BoolVar(&opt.ForceVersionWithoutMigrations, "force", false,
-		"force version of migration to set in database without running any migrations")
-	flag.BoolVar(&opt.RefreshSchema, "refresh", false,
-		"refresh database, should be set for first run (when DB is empty)")
-	flag.Parse()
+//go:embed sql/*.sql
+var sqlMigrations embed.FS
 
-	m, err := migrate.New(opt)
-	if err != nil {
+func main() {
+	// SQL migrations are registered next to the Go ones from the other files of this package.
+	if err := migrate.AddMigrationsFS(sqlMigrations, "sql"); err != nil {
 		log.Fatal(err)
 	}
 
-	err = m.Migrate()
-	if err != nil {
-		log.Fatal(err)
-	}
+	os.Exit(migrate.RunCLI(os.Args[1:], os.Stdout, os.Stderr))
 }
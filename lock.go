@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLockKey is the advisory lock key used when Options.LockKey is not set.
+	DefaultLockKey int64 = 0x67_6f_70_67_6d_69_67 // "gopgmig"
+
+	// DefaultLockTimeout is the lock wait timeout used when Options.LockTimeout is not set.
+	DefaultLockTimeout = 10 * time.Minute
+
+	lockRetryInterval = time.Second
+)
+
+var (
+	errLockTimeout = errors.New("timed out waiting for the migration lock")
+	errLockNotHeld = errors.New("advisory lock was not held")
+)
+
+type lockHolder struct {
+	PID             int
+	User            string
+	ApplicationName string
+	ClientAddr      string
+}
+
+func (h lockHolder) String() string {
+	description := fmt.Sprintf("pid %d", h.PID)
+
+	if h.User != "" {
+		description += ", user " + h.User
+	}
+
+	if h.ApplicationName != "" {
+		description += ", application " + h.ApplicationName
+	}
+
+	if h.ClientAddr != "" {
+		description += ", client " + h.ClientAddr
+	}
+
+	return description
+}
+
+// lock takes the session-level advisory lock, waiting for other migrations up to the configured timeout.
+func (m *migrationTask) lock(ctx context.Context) error {
+	ctx, cancel := context.WithTimeoutCause(ctx, m.opt.LockTimeout,
+		fmt.Errorf("%w: waited %s for lock %d", errLockTimeout, m.opt.LockTimeout, m.opt.LockKey))
+	defer cancel()
+
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for attempt := 0; ; attempt++ {
+		locked, err := m.repo.Lock(ctx, m.opt.LockKey)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", context.Cause(ctx))
+			}
+
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		if locked {
+			return nil
+		}
+
+		if attempt == 0 {
+			m.logLockHolders(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to acquire migration lock: %w", context.Cause(ctx))
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *migrationTask) logLockHolders(ctx context.Context) {
+	holders, err := m.repo.GetLockHolders(ctx, m.opt.LockKey)
+	if err != nil {
+		m.opt.Logger.WarnContext(ctx, "waiting for migration lock, failed to look up its holder",
+			"lock_key", m.opt.LockKey, LogKeyError, err)
+
+		return
+	}
+
+	if len(holders) == 0 {
+		m.opt.Logger.InfoContext(ctx, "waiting for migration lock held by another session", "lock_key", m.opt.LockKey)
+
+		return
+	}
+
+	descriptions := make([]string, len(holders))
+	for i := range holders {
+		descriptions[i] = holders[i].String()
+	}
+
+	m.opt.Logger.InfoContext(ctx, "waiting for migration lock held by another session",
+		"lock_key", m.opt.LockKey, "holders", strings.Join(descriptions, "; "))
+}
+
+// withLock runs fn while holding the advisory lock, releasing it afterwards.
+func (m *migrationTask) withLock(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if unlockErr := m.unlock(ctx); unlockErr != nil {
+			err = errors.Join(err, unlockErr)
+		}
+	}()
+
+	if err = m.lock(ctx); err != nil {
+		return err
+	}
+
+	return fn(ctx)
+}
+
+// unlock releases the advisory lock even when ctx is already cancelled.
+func (m *migrationTask) unlock(ctx context.Context) error {
+	if err := m.repo.Unlock(context.WithoutCancel(ctx), m.opt.LockKey); err != nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+
+	return nil
+}
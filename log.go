@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Attribute keys of the logged migration events.
+const (
+	LogKeyNumber    = "number"
+	LogKeyName      = "name"
+	LogKeyDirection = "direction"
+	LogKeyDuration  = "duration"
+	LogKeyError     = "error"
+)
+
+// InfoLogger defines info level logger, passes go-sprintf-friendly format & arguments.
+//
+// Deprecated: use Options.Logger. InfoLogger receives the info, warning and error level events,
+// but not the debug ones, with their attributes formatted inline.
+type InfoLogger func(format string, args ...interface{})
+
+// newLogger returns the logger set in the options, the one wrapping Options.LogInfo, or slog.Default.
+func newLogger(opt Options) *slog.Logger {
+	switch {
+	case opt.Logger != nil:
+		return opt.Logger
+	case opt.LogInfo != nil:
+		return slog.New(&infoLoggerHandler{logInfo: opt.LogInfo, attrs: nil, group: ""})
+	default:
+		return slog.Default()
+	}
+}
+
+// migrationAttrs returns the attributes identifying a migration run in direction.
+func migrationAttrs(m *migration, direction Direction) []any {
+	return []any{
+		slog.Uint64(LogKeyNumber, uint64(m.Number)),
+		slog.String(LogKeyName, m.Name),
+		slog.String(LogKeyDirection, string(direction)),
+	}
+}
+
+// infoLoggerHandler adapts the deprecated InfoLogger to slog, formatting the attributes as key=value pairs.
+type infoLoggerHandler struct {
+	logInfo InfoLogger
+	attrs   []slog.Attr
+	group   string
+}
+
+func (h *infoLoggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+//nolint:gocritic // slog.Handler passes the record by value
+func (h *infoLoggerHandler) Handle(_ context.Context, record slog.Record) error {
+	var message strings.Builder
+
+	message.WriteString(record.Message)
+
+	for _, attr := range h.attrs {
+		fmt.Fprintf(&message, " %s=%v", attr.Key, attr.Value)
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		fmt.Fprintf(&message, " %s%s=%v", h.group, attr.Key, attr.Value)
+
+		return true
+	})
+
+	h.logInfo("%s", message.String())
+
+	return nil
+}
+
+func (h *infoLoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	withAttrs := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	withAttrs = append(withAttrs, h.attrs...)
+
+	for _, attr := range attrs {
+		withAttrs = append(withAttrs, slog.Attr{Key: h.group + attr.Key, Value: attr.Value})
+	}
+
+	return &infoLoggerHandler{logInfo: h.logInfo, attrs: withAttrs, group: h.group}
+}
+
+func (h *infoLoggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &infoLoggerHandler{logInfo: h.logInfo, attrs: h.attrs, group: h.group + name + "."}
+}
@@ -0,0 +1,85 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	someErr := errors.New("test-err") //nolint:goerr113 // used for tests only
+
+	repo := new(mockRepository)
+	repo.On("Lock", mock.Anything, mock.Anything).Return(true, nil)
+	repo.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+	repo.On("InsertAuditEvent", mock.Anything, mock.Anything).Return(nil)
+	repo.On("EnsureMigrationTable", mock.Anything).Return(nil)
+	repo.On("GetAppliedMigrations", mock.Anything).Return(appliedMigrations(1), nil)
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionUp).Return(nil).Once()
+	repo.On("ApplyMigration", mock.Anything, mock.Anything, DirectionUp).Return(someErr).Once()
+
+	var output bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&output, nil))
+
+	err := performMigrateTaskWithMigrations(t, repo, Options{Logger: logger})
+	assert.ErrorIs(t, err, someErr)
+
+	var events []map[string]any
+
+	for _, line := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		var event map[string]any
+		if assert.NoError(t, json.Unmarshal(line, &event)) {
+			events = append(events, event)
+		}
+	}
+
+	var applied, failed map[string]any
+
+	for _, event := range events {
+		switch event["msg"] {
+		case "applied migration":
+			applied = event
+		case "migration failed":
+			failed = event
+		}
+	}
+
+	if assert.NotNil(t, applied) {
+		assert.Equal(t, "INFO", applied["level"])
+		assert.EqualValues(t, 2, applied[LogKeyNumber])
+		assert.Equal(t, "Add Email For Users", applied[LogKeyName])
+		assert.Equal(t, "up", applied[LogKeyDirection])
+		assert.Contains(t, applied, LogKeyDuration)
+	}
+
+	if assert.NotNil(t, failed) {
+		assert.Equal(t, "ERROR", failed["level"])
+		assert.EqualValues(t, 3, failed[LogKeyNumber])
+		assert.Equal(t, "test-err", failed[LogKeyError])
+	}
+}
+
+func TestInfoLoggerHandler(t *testing.T) {
+	t.Parallel()
+
+	var messages []string
+
+	logger := newLogger(Options{LogInfo: func(format string, args ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}})
+
+	logger.Debug("hidden")
+	logger.With(LogKeyNumber, 1).WithGroup("lock").Info("waiting", "key", 42)
+	logger.Warn("repairing", LogKeyName, "users")
+
+	assert.Equal(t, []string{"waiting number=1 lock.key=42", "repairing name=users"}, messages)
+}
@@ -1,33 +1,46 @@
 package migrate
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
-type Tx struct {
-	*sql.Tx
-}
-
 // Migration defines a single version of a migration to run.
 type Migration struct {
 	Name   string
 	Number uint
 
+	// Checksum identifies the version of the migration, e.g. a hash of its SQL or a version string.
+	// A changed checksum of an applied migration fails the migration unless Options.RepairChecksums is set.
+	// Optional, migrations without a checksum are not validated.
+	Checksum string
+
+	// NoTransaction runs the migration outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY.
+	// Up and Down then get a Tx scoped to a single connection, see Tx.Conn.
+	// A failed migration is not rolled back and is reported with ManualInterventionError.
+	NoTransaction bool
+
+	// Irreversible marks a migration that cannot be reverted, e.g. one dropping data.
+	// Migrations without Down are irreversible as well. Plans reverting such migrations fail before running anything.
+	Irreversible bool
+
 	Up   func(tx Tx) error
 	Down func(tx Tx) error
 }
 
-//nolint:gochecknoglobals // allow global var as it's short-lived
-var migrations []*Migration
-
 type migration struct {
 	ID        uint
 	CreatedAt time.Time
 	Name      string
 	Number    uint
+	Checksum  string
+
+	NoTransaction bool
+	Dirty         bool
+	Irreversible  bool
+	Baselined     bool
 
 	Forwards  func(tx Tx) error `pg:"-"`
 	Backwards func(tx Tx) error `pg:"-"`
@@ -37,12 +50,9 @@ var (
 	errDuplicateMigrationVersion  = errors.New("duplicate migration version is not allowed")
 	errMigrationIsMissing         = errors.New("migration is missing")
 	errMigrationNameCannotBeEmpty = errors.New("migration name cannot be empty")
+	errMigrationNumberOverflow    = errors.New("migration number does not fit in a BIGINT")
 )
 
-func AddMigration(m *Migration) {
-	migrations = append(migrations, m)
-}
-
 func validateMigrations(migrations []*Migration) error {
 	for migrationIdx := range migrations {
 		for migrationSecondaryIdx := range migrations {
@@ -55,6 +65,13 @@ func validateMigrations(migrations []*Migration) error {
 			}
 		}
 
+		if uint64(migrations[migrationIdx].Number) > math.MaxInt64 {
+			return fmt.Errorf("%s (%d) number is above %d: %w",
+				migrations[migrationIdx].Name, migrations[migrationIdx].Number, int64(math.MaxInt64),
+				errMigrationNumberOverflow,
+			)
+		}
+
 		if migrations[migrationIdx].Name == "" {
 			return fmt.Errorf("%s (%d) name cannot be empty: %w",
 				migrations[migrationIdx].Name, migrations[migrationIdx].Number,
@@ -79,10 +96,13 @@ func mapMigrations(rawMigrations []*Migration) []*migration {
 	for migrationIdx := range rawMigrations {
 		//nolint:exhaustivestruct,exhaustruct // ID & created_at are not used
 		migrations[migrationIdx] = &migration{
-			Name:      rawMigrations[migrationIdx].Name,
-			Number:    rawMigrations[migrationIdx].Number,
-			Forwards:  rawMigrations[migrationIdx].Up,
-			Backwards: rawMigrations[migrationIdx].Down,
+			Name:          rawMigrations[migrationIdx].Name,
+			Number:        rawMigrations[migrationIdx].Number,
+			Checksum:      rawMigrations[migrationIdx].Checksum,
+			NoTransaction: rawMigrations[migrationIdx].NoTransaction,
+			Irreversible:  rawMigrations[migrationIdx].Irreversible || rawMigrations[migrationIdx].Down == nil,
+			Forwards:      rawMigrations[migrationIdx].Up,
+			Backwards:     rawMigrations[migrationIdx].Down,
 		}
 	}
 
@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	errMalformedMigrationFileName = errors.New("malformed migration file name, expected <number>_<name>.<up|down>.sql")
+	errDuplicateMigrationFile     = errors.New("duplicate migration file")
+	errOrphanDownMigrationFile    = errors.New("down migration file has no matching up migration file")
+)
+
+var migrationFileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const (
+	// noTransactionDirective on the first line of an up or down file runs the migration without a transaction,
+	// in both directions.
+	noTransactionDirective = "-- migrate:no-transaction"
+
+	sqlFileSuffix     = ".sql"
+	upFileDirection   = "up"
+	downFileDirection = "down"
+)
+
+type sqlMigrationFiles struct {
+	number   uint
+	name     string
+	upFile   string
+	upSQL    string
+	downFile string
+	downSQL  string
+}
+
+// LoadMigrationsFS reads SQL migrations from dir of fsys, e.g. an embed.FS.
+// Files are named <number>_<name>.up.sql and <number>_<name>.down.sql, e.g. 0001_create_users.up.sql.
+// The number and name of the migration are taken from the file name, with underscores in the name read as spaces.
+// The checksum of the migration is the SHA-256 hash of the up file, followed by the down file when there is one,
+// so that editing either is detected.
+// Starting a file with the "-- migrate:no-transaction" line runs the migration without a transaction,
+// see Migration.NoTransaction; such files should hold a single statement. The directive applies to the migration,
+// so a directive in either file runs both the up and the down file without a transaction.
+// Down files are optional, other files without the .sql extension are ignored.
+func LoadMigrationsFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory %s: %w", dir, err)
+	}
+
+	filesByNumber := make(map[uint]*sqlMigrationFiles)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sqlFileSuffix) {
+			continue
+		}
+
+		if err = readMigrationFile(fsys, path.Join(dir, entry.Name()), filesByNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	loaded := make([]*Migration, 0, len(filesByNumber))
+
+	for _, files := range filesByNumber {
+		if files.upFile == "" {
+			return nil, fmt.Errorf("%s: %w", files.downFile, errOrphanDownMigrationFile)
+		}
+
+		loaded = append(loaded, files.migration())
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].Number < loaded[j].Number
+	})
+
+	return loaded, nil
+}
+
+func readMigrationFile(fsys fs.FS, filePath string, filesByNumber map[uint]*sqlMigrationFiles) error {
+	match := migrationFileNamePattern.FindStringSubmatch(path.Base(filePath))
+	if match == nil {
+		return fmt.Errorf("%s: %w", filePath, errMalformedMigrationFileName)
+	}
+
+	number, err := strconv.ParseUint(match[1], 10, strconv.IntSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("%s: %w: %s", filePath, errVersionOverflow, match[1])
+		}
+
+		return fmt.Errorf("%s: %w", filePath, errMalformedMigrationFileName)
+	}
+
+	content, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read migration file %s: %w", filePath, err)
+	}
+
+	name := strings.ReplaceAll(match[2], "_", " ")
+
+	files, ok := filesByNumber[uint(number)]
+	if !ok {
+		//nolint:exhaustivestruct,exhaustruct // files are filled in below
+		files = &sqlMigrationFiles{number: uint(number), name: name}
+		filesByNumber[uint(number)] = files
+	}
+
+	if files.name != name {
+		return fmt.Errorf("%s and %s: %w", filePath, files.anyFile(), errDuplicateMigrationFile)
+	}
+
+	switch match[3] {
+	case upFileDirection:
+		if files.upFile != "" {
+			return fmt.Errorf("%s and %s: %w", filePath, files.upFile, errDuplicateMigrationFile)
+		}
+
+		files.upFile, files.upSQL = filePath, string(content)
+	case downFileDirection:
+		if files.downFile != "" {
+			return fmt.Errorf("%s and %s: %w", filePath, files.downFile, errDuplicateMigrationFile)
+		}
+
+		files.downFile, files.downSQL = filePath, string(content)
+	}
+
+	return nil
+}
+
+func (f *sqlMigrationFiles) anyFile() string {
+	if f.upFile != "" {
+		return f.upFile
+	}
+
+	return f.downFile
+}
+
+func (f *sqlMigrationFiles) migration() *Migration {
+	//nolint:exhaustivestruct,exhaustruct // Down is set only when there is a down file
+	m := &Migration{
+		Name:     f.name,
+		Number:   f.number,
+		Checksum: f.checksum(),
+		Up:       execSQL(f.upFile, f.upSQL),
+
+		NoTransaction: hasNoTransactionDirective(f.upSQL) || hasNoTransactionDirective(f.downSQL),
+	}
+
+	if f.downFile != "" {
+		m.Down = execSQL(f.downFile, f.downSQL)
+	}
+
+	return m
+}
+
+// checksum hashes the up file, and the down file separated by a NUL byte that SQL text does not contain.
+// Migrations without a down file keep the hash of the up file alone.
+func (f *sqlMigrationFiles) checksum() string {
+	if f.downFile == "" {
+		return sqlChecksum(f.upSQL)
+	}
+
+	return sqlChecksum(f.upSQL + "\x00" + f.downSQL)
+}
+
+func hasNoTransactionDirective(query string) bool {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(query), "\n")
+
+	return strings.TrimSpace(firstLine) == noTransactionDirective
+}
+
+func execSQL(fileName, query string) func(tx Tx) error {
+	return func(tx Tx) error {
+		if _, err := tx.ExecContext(tx.Context(), query); err != nil {
+			return fmt.Errorf("failed to execute %s: %w", fileName, err)
+		}
+
+		return nil
+	}
+}
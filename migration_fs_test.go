@@ -0,0 +1,107 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrationsFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"migrations/0004_add_phone_for_users.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN phone TEXT")},
+		"migrations/0004_add_phone_for_users.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN phone")},
+		"migrations/0005_add_index.up.sql":             {Data: []byte("CREATE INDEX users_phone ON users (phone)")},
+		"migrations/0006_index_phone.up.sql": {
+			Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY users_phone_idx ON users (phone)"),
+		},
+		"migrations/README.md": {Data: []byte("ignored")},
+	}
+
+	loaded, err := LoadMigrationsFS(fsys, "migrations")
+	assert.NoError(t, err)
+
+	if assert.Len(t, loaded, 3) {
+		assert.Equal(t, uint(4), loaded[0].Number)
+		assert.Equal(t, "add phone for users", loaded[0].Name)
+		assert.Equal(t, sqlChecksum("ALTER TABLE users ADD COLUMN phone TEXT\x00ALTER TABLE users DROP COLUMN phone"),
+			loaded[0].Checksum, "up & down files")
+		assert.NotNil(t, loaded[0].Up)
+		assert.NotNil(t, loaded[0].Down)
+
+		assert.Equal(t, uint(5), loaded[1].Number)
+		assert.Equal(t, "add index", loaded[1].Name)
+		assert.NotNil(t, loaded[1].Up)
+		assert.Nil(t, loaded[1].Down)
+		assert.Equal(t, sqlChecksum("CREATE INDEX users_phone ON users (phone)"), loaded[1].Checksum, "up file only")
+		assert.False(t, loaded[1].NoTransaction)
+
+		assert.True(t, loaded[2].NoTransaction)
+	}
+
+	assert.NoError(t, validateMigrations(append(prepareMigrations(), loaded...)), "Mixed With Go Migrations")
+
+	loaded, err = LoadMigrationsFS(fstest.MapFS{
+		"migrations/0007_index_email.up.sql": {Data: []byte("CREATE INDEX users_email_idx ON users (email)")},
+		"migrations/0007_index_email.down.sql": {
+			Data: []byte("-- migrate:no-transaction\nDROP INDEX CONCURRENTLY users_email_idx"),
+		},
+	}, "migrations")
+	if assert.NoError(t, err) && assert.Len(t, loaded, 1) {
+		assert.True(t, loaded[0].NoTransaction, "directive of the down file applies to both directions")
+	}
+}
+
+func TestLoadMigrationsFSErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		fsys        fstest.MapFS
+		expectedErr error
+	}{
+		{
+			name:        "malformed name",
+			fsys:        fstest.MapFS{"migrations/create_users.up.sql": {Data: []byte("SELECT 1")}},
+			expectedErr: errMalformedMigrationFileName,
+		},
+		{
+			name:        "malformed direction",
+			fsys:        fstest.MapFS{"migrations/0001_create_users.sql": {Data: []byte("SELECT 1")}},
+			expectedErr: errMalformedMigrationFileName,
+		},
+		{
+			name:        "orphan down",
+			fsys:        fstest.MapFS{"migrations/0001_create_users.down.sql": {Data: []byte("SELECT 1")}},
+			expectedErr: errOrphanDownMigrationFile,
+		},
+		{
+			name: "mismatched names",
+			fsys: fstest.MapFS{
+				"migrations/0001_create_users.up.sql":    {Data: []byte("SELECT 1")},
+				"migrations/0001_create_people.down.sql": {Data: []byte("SELECT 1")},
+			},
+			expectedErr: errDuplicateMigrationFile,
+		},
+		{
+			name: "duplicate up",
+			fsys: fstest.MapFS{
+				"migrations/0001_create_users.up.sql": {Data: []byte("SELECT 1")},
+				"migrations/1_create_users.up.sql":    {Data: []byte("SELECT 1")},
+			},
+			expectedErr: errDuplicateMigrationFile,
+		},
+		{
+			name:        "number overflow",
+			fsys:        fstest.MapFS{"migrations/99999999999999999999_create_users.up.sql": {Data: []byte("SELECT 1")}},
+			expectedErr: errVersionOverflow,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := LoadMigrationsFS(testCase.fsys, "migrations")
+		assert.ErrorIs(t, err, testCase.expectedErr, testCase.name)
+	}
+}
@@ -2,20 +2,52 @@
 
 package migrate
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // mockRepository is an autogenerated mock type for the repository type
 type mockRepository struct {
 	mock.Mock
 }
 
-// ApplyMigration provides a mock function with given fields: txFunc
-func (_m *mockRepository) ApplyMigration(txFunc func(Tx) error) error {
-	ret := _m.Called(txFunc)
+// ApplyMigration provides a mock function with given fields: ctx, m, direction
+func (_m *mockRepository) ApplyMigration(ctx context.Context, m *migration, direction Direction) error {
+	ret := _m.Called(ctx, m, direction)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *migration, Direction) error); ok {
+		r0 = rf(ctx, m, direction)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ApplyMigrationWithoutTransaction provides a mock function with given fields: ctx, m, direction
+func (_m *mockRepository) ApplyMigrationWithoutTransaction(ctx context.Context, m *migration, direction Direction) error {
+	ret := _m.Called(ctx, m, direction)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *migration, Direction) error); ok {
+		r0 = rf(ctx, m, direction)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BaselineMigrations provides a mock function with given fields: ctx, migrations
+func (_m *mockRepository) BaselineMigrations(ctx context.Context, migrations []*migration) error {
+	ret := _m.Called(ctx, migrations)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(func(Tx) error) error); ok {
-		r0 = rf(txFunc)
+	if rf, ok := ret.Get(0).(func(context.Context, []*migration) error); ok {
+		r0 = rf(ctx, migrations)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -23,13 +55,13 @@ func (_m *mockRepository) ApplyMigration(txFunc func(Tx) error) error {
 	return r0
 }
 
-// DropSchema provides a mock function with given fields: schemaName
-func (_m *mockRepository) DropSchema(schemaName string) error {
-	ret := _m.Called(schemaName)
+// ClearMigrations provides a mock function with given fields: ctx
+func (_m *mockRepository) ClearMigrations(ctx context.Context) error {
+	ret := _m.Called(ctx)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(schemaName)
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -37,8 +69,8 @@ func (_m *mockRepository) DropSchema(schemaName string) error {
 	return r0
 }
 
-// EnsureMigrationTable provides a mock function with given fields:
-func (_m *mockRepository) EnsureMigrationTable() error {
+// Close provides a mock function with given fields:
+func (_m *mockRepository) Close() error {
 	ret := _m.Called()
 
 	var r0 error
@@ -51,20 +83,101 @@ func (_m *mockRepository) EnsureMigrationTable() error {
 	return r0
 }
 
-// GetLatestMigrationNumber provides a mock function with given fields:
-func (_m *mockRepository) GetLatestMigrationNumber() (uint, error) {
-	ret := _m.Called()
+// DropSchema provides a mock function with given fields: ctx, schemaName
+func (_m *mockRepository) DropSchema(ctx context.Context, schemaName string) error {
+	ret := _m.Called(ctx, schemaName)
 
-	var r0 uint
-	if rf, ok := ret.Get(0).(func() uint); ok {
-		r0 = rf()
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, schemaName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnsureAuditTable provides a mock function with given fields: ctx
+func (_m *mockRepository) EnsureAuditTable(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnsureMigrationTable provides a mock function with given fields: ctx
+func (_m *mockRepository) EnsureMigrationTable(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
 	} else {
-		r0 = ret.Get(0).(uint)
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ForceMigration provides a mock function with given fields: ctx, m, direction
+func (_m *mockRepository) ForceMigration(ctx context.Context, m *migration, direction Direction) error {
+	ret := _m.Called(ctx, m, direction)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *migration, Direction) error); ok {
+		r0 = rf(ctx, m, direction)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAppliedMigrations provides a mock function with given fields: ctx
+func (_m *mockRepository) GetAppliedMigrations(ctx context.Context) ([]*migration, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*migration
+	if rf, ok := ret.Get(0).(func(context.Context) []*migration); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*migration)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditEvents provides a mock function with given fields: ctx
+func (_m *mockRepository) GetAuditEvents(ctx context.Context) ([]AuditEvent, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []AuditEvent
+	if rf, ok := ret.Get(0).(func(context.Context) []AuditEvent); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]AuditEvent)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -72,13 +185,157 @@ func (_m *mockRepository) GetLatestMigrationNumber() (uint, error) {
 	return r0, r1
 }
 
-// InsertMigration provides a mock function with given fields: m
-func (_m *mockRepository) InsertMigration(m *migration) error {
-	ret := _m.Called(m)
+// GetLegacyAppliedMigrations provides a mock function with given fields: ctx
+func (_m *mockRepository) GetLegacyAppliedMigrations(ctx context.Context) ([]*migration, bool, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*migration
+	if rf, ok := ret.Get(0).(func(context.Context) []*migration); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*migration)
+		}
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(context.Context) bool); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetLockHolders provides a mock function with given fields: ctx, key
+func (_m *mockRepository) GetLockHolders(ctx context.Context, key int64) ([]lockHolder, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 []lockHolder
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []lockHolder); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]lockHolder)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertAuditEvent provides a mock function with given fields: ctx, event
+func (_m *mockRepository) InsertAuditEvent(ctx context.Context, event *AuditEvent) error {
+	ret := _m.Called(ctx, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *AuditEvent) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lock provides a mock function with given fields: ctx, key
+func (_m *mockRepository) Lock(ctx context.Context, key int64) (bool, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MigrationTableExists provides a mock function with given fields: ctx
+func (_m *mockRepository) MigrationTableExists(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MoveLegacyMigrationTable provides a mock function with given fields: ctx
+func (_m *mockRepository) MoveLegacyMigrationTable(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveDirtyMigrations provides a mock function with given fields: ctx
+func (_m *mockRepository) RemoveDirtyMigrations(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unlock provides a mock function with given fields: ctx, key
+func (_m *mockRepository) Unlock(ctx context.Context, key int64) error {
+	ret := _m.Called(ctx, key)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*migration) error); ok {
-		r0 = rf(m)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, key)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -86,13 +343,13 @@ func (_m *mockRepository) InsertMigration(m *migration) error {
 	return r0
 }
 
-// RemoveMigrationsAfter provides a mock function with given fields: number
-func (_m *mockRepository) RemoveMigrationsAfter(number uint) error {
-	ret := _m.Called(number)
+// UpdateMigrationChecksum provides a mock function with given fields: ctx, number, checksum
+func (_m *mockRepository) UpdateMigrationChecksum(ctx context.Context, number uint, checksum string) error {
+	ret := _m.Called(ctx, number, checksum)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uint) error); ok {
-		r0 = rf(number)
+	if rf, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
+		r0 = rf(ctx, number, checksum)
 	} else {
 		r0 = ret.Error(0)
 	}
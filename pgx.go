@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Drivers to open DatabaseURI with, see Options.Driver.
+const (
+	// DriverPQ opens the database with github.com/lib/pq.
+	DriverPQ = "postgres"
+
+	// DriverPgx opens the database with github.com/jackc/pgx/v5, so that migrations can use it natively,
+	// see Tx.WithPgxConn.
+	DriverPgx = "pgx"
+)
+
+var (
+	errUnknownDriver    = errors.New("unknown driver")
+	errNotPgxConnection = errors.New("migration does not run on a pgx connection")
+)
+
+// sqlDriverName returns the database/sql driver name of driver, DriverPQ when it is empty.
+func sqlDriverName(driver string) (string, error) {
+	switch driver {
+	case "", DriverPQ:
+		return DriverPQ, nil
+	case DriverPgx:
+		return DriverPgx, nil
+	default:
+		return "", fmt.Errorf("%q: %w", driver, errUnknownDriver)
+	}
+}
+
+// WithPgxConn calls fn with the pgx connection the migration runs on, e.g. for CopyFrom or SendBatch.
+// It is not a pgx.Tx: a regular migration's database/sql transaction is open on the connection,
+// so what fn sends joins that transaction, which Migrate commits or rolls back.
+// fn must not Begin, Commit nor Rollback on the connection.
+// It fails unless the database was opened with DriverPgx or from Options.PgxPool.
+func (tx Tx) WithPgxConn(fn func(conn *pgx.Conn) error) error {
+	if tx.raw == nil {
+		return errNotPgxConnection
+	}
+
+	//nolint:wrapcheck // errors of fn are passed through as is
+	return tx.raw.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errNotPgxConnection
+		}
+
+		return fn(stdlibConn.Conn())
+	})
+}
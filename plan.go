@@ -0,0 +1,428 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	errOutOfOrderMigrations = errors.New(
+		"out of order migrations are not applied, set AllowOutOfOrder to apply them")
+	errRedoUnknownMigration  = errors.New("latest applied migration to redo is not known")
+	errConflictingTargets    = errors.New("only one of VersionNumberToApply, Steps, Redo and RollbackAll can be set")
+	errRollbackUnknown       = errors.New("applied migrations missing from code cannot be rolled back")
+	errIrreversibleMigration = errors.New("irreversible migration cannot be reverted")
+	errMissingUpMigration    = errors.New("migration without Up cannot be applied")
+)
+
+// Direction tells whether a planned step moves the database version up or down.
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Operation tells how a planned step is carried out.
+type Operation string
+
+const (
+	// OperationForward runs the Up migration and records it as applied.
+	OperationForward Operation = "forward"
+	// OperationBackward runs the Down migration and removes its record.
+	OperationBackward Operation = "backward"
+	// OperationForce records the migration as the latest applied one without running anything.
+	OperationForce Operation = "force"
+)
+
+// PlanStep is a single step Migrate takes, in the order it takes them.
+type PlanStep struct {
+	Direction Direction `json:"direction"`
+	Operation Operation `json:"operation"`
+	Number    uint      `json:"number"`
+	Name      string    `json:"name"`
+
+	migration *migration
+}
+
+func (s PlanStep) String() string {
+	return fmt.Sprintf("%s %s migration %d (%s)", s.Operation, s.Direction, s.Number, s.Name)
+}
+
+func newPlanStep(m *migration, direction Direction, operation Operation) PlanStep {
+	return PlanStep{
+		Direction: direction,
+		Operation: operation,
+		Number:    m.Number,
+		Name:      m.Name,
+		migration: m,
+	}
+}
+
+// appliedSet indexes the recorded migrations by number.
+type appliedSet map[uint]*migration
+
+func newAppliedSet(applied []*migration) appliedSet {
+	set := make(appliedSet, len(applied))
+	for _, m := range applied {
+		set[m.Number] = m
+	}
+
+	return set
+}
+
+func (s appliedSet) contains(number uint) bool {
+	_, ok := s[number]
+
+	return ok
+}
+
+func (s appliedSet) latest() uint {
+	var latest uint
+
+	for number := range s {
+		if number > latest {
+			latest = number
+		}
+	}
+
+	return latest
+}
+
+// apply updates the set with a step taken, the way the repository records it.
+func (s appliedSet) apply(step PlanStep) {
+	if step.Operation != OperationForward {
+		for number := range s {
+			if number >= step.Number {
+				delete(s, number)
+			}
+		}
+	}
+
+	if step.Operation != OperationBackward {
+		s[step.Number] = step.migration
+	}
+}
+
+// validateTarget fails when the options set more than one target to migrate to.
+func validateTarget(opt Options) error {
+	targets := 0
+
+	for _, isSet := range []bool{opt.VersionNumberToApply != 0, opt.Steps != 0, opt.Redo, opt.RollbackAll} {
+		if isSet {
+			targets++
+		}
+	}
+
+	if targets > 1 {
+		return errConflictingTargets
+	}
+
+	return nil
+}
+
+// plan computes the steps to take from the applied migrations according to the options,
+// refusing the plans that would run a migration in a direction it does not support.
+func (m *migrationTask) plan(applied appliedSet) ([]PlanStep, error) {
+	steps, err := m.planSteps(applied)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		switch {
+		case step.Operation == OperationBackward && step.migration.Irreversible:
+			return nil, fmt.Errorf("%w: %d (%s)", errIrreversibleMigration, step.Number, step.Name)
+		case step.Operation == OperationForward && step.migration.Forwards == nil:
+			return nil, fmt.Errorf("%w: %d (%s)", errMissingUpMigration, step.Number, step.Name)
+		}
+	}
+
+	return steps, nil
+}
+
+func (m *migrationTask) planSteps(applied appliedSet) ([]PlanStep, error) {
+	if m.opt.ForceVersionWithoutMigrations {
+		return m.planForceVersionWithoutMigrations(applied.latest())
+	}
+
+	if m.opt.PrintInfoAndExit {
+		return nil, nil
+	}
+
+	// Going backward is checked against the applied migrations even when none are known.
+	switch {
+	case m.opt.Redo:
+		return m.planRedo(applied)
+	case m.opt.RollbackAll:
+		return m.planRollbackAll(applied)
+	case m.opt.Steps < 0:
+		return m.planBackwardSteps(applied)
+	case len(m.migrations) == 0:
+		return nil, nil
+	case m.opt.Steps > 0:
+		steps, err := m.planForwardMigrations(applied, 0)
+
+		return firstSteps(steps, m.opt.Steps), err
+	}
+
+	versionNumberToApply := m.opt.VersionNumberToApply
+	if versionNumberToApply == 0 {
+		versionNumberToApply = m.getLastMigrationNumber()
+	} else if unknown := m.unknownApplied(applied, versionNumberToApply+1); len(unknown) > 0 {
+		// Reverting to the version would remove the records of applied migrations not known without reverting them.
+		return nil, fmt.Errorf("%w: %v", errRollbackUnknown, unknown)
+	}
+
+	if versionNumberToApply < applied.latest() {
+		return m.planBackwardMigrations(applied, versionNumberToApply), nil
+	}
+
+	return m.planForwardMigrations(applied, versionNumberToApply)
+}
+
+// planRedo reverts the latest applied migration and applies it again.
+func (m *migrationTask) planRedo(applied appliedSet) ([]PlanStep, error) {
+	latest := applied.latest()
+	if latest == 0 {
+		return nil, nil
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Number == latest {
+			return []PlanStep{
+				newPlanStep(migration, DirectionDown, OperationBackward),
+				newPlanStep(migration, DirectionUp, OperationForward),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", errRedoUnknownMigration, latest)
+}
+
+// planRollbackAll reverts every applied migration, latest first, leaving the history empty.
+// It fails when some applied migrations are not known, as they cannot be reverted.
+func (m *migrationTask) planRollbackAll(applied appliedSet) ([]PlanStep, error) {
+	if unknown := m.unknownApplied(applied, 0); len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %v", errRollbackUnknown, unknown)
+	}
+
+	return m.planBackwardMigrations(applied, 0), nil
+}
+
+// planBackwardSteps reverts the latest applied migrations, as many as Steps tells.
+// It fails when applied migrations not known sit above the reverted ones, as their records would be removed
+// without reverting them.
+func (m *migrationTask) planBackwardSteps(applied appliedSet) ([]PlanStep, error) {
+	steps := firstSteps(m.planBackwardMigrations(applied, 0), -m.opt.Steps)
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	if unknown := m.unknownApplied(applied, steps[len(steps)-1].Number); len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %v", errRollbackUnknown, unknown)
+	}
+
+	return steps, nil
+}
+
+// unknownApplied returns the numbers of the applied migrations not known, from the given one up, in order.
+func (m *migrationTask) unknownApplied(applied appliedSet, from uint) []uint {
+	known := make(map[uint]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Number] = true
+	}
+
+	var unknown []uint
+
+	for number := range applied {
+		if number >= from && !known[number] {
+			unknown = append(unknown, number)
+		}
+	}
+
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+
+	return unknown
+}
+
+// firstSteps limits the steps to the first count ones.
+func firstSteps(steps []PlanStep, count int) []PlanStep {
+	if len(steps) > count {
+		return steps[:count]
+	}
+
+	return steps
+}
+
+func (m *migrationTask) planForceVersionWithoutMigrations(lastAppliedMigrationNumber uint) ([]PlanStep, error) {
+	for _, migration := range m.migrations {
+		if migration.Number != m.opt.VersionNumberToApply {
+			continue
+		}
+
+		direction := DirectionUp
+		if migration.Number < lastAppliedMigrationNumber {
+			direction = DirectionDown
+		}
+
+		return []PlanStep{newPlanStep(migration, direction, OperationForce)}, nil
+	}
+
+	return nil, errNoMigrationVersion
+}
+
+func (m *migrationTask) planBackwardMigrations(applied appliedSet, versionNumberToApply uint) []PlanStep {
+	m.sortMigrationsDesc()
+
+	var steps []PlanStep
+
+	for _, migration := range m.migrations {
+		if migration.Number <= versionNumberToApply {
+			break
+		}
+
+		if !applied.contains(migration.Number) {
+			continue
+		}
+
+		steps = append(steps, newPlanStep(migration, DirectionDown, OperationBackward))
+	}
+
+	return steps
+}
+
+// planForwardMigrations applies every pending migration up to the target in number order.
+// Pending migrations below the latest applied one are out of order and fail the plan unless allowed.
+func (m *migrationTask) planForwardMigrations(applied appliedSet, versionNumberToApply uint) ([]PlanStep, error) {
+	m.sortMigrationsAsc()
+
+	lastAppliedMigrationNumber := applied.latest()
+
+	var (
+		steps      []PlanStep
+		outOfOrder []string
+	)
+
+	for _, migration := range m.migrations {
+		if migration.Number > versionNumberToApply && versionNumberToApply != 0 {
+			break
+		}
+
+		if applied.contains(migration.Number) {
+			continue
+		}
+
+		if migration.Number < lastAppliedMigrationNumber {
+			outOfOrder = append(outOfOrder, fmt.Sprintf("%d (%s)", migration.Number, migration.Name))
+		}
+
+		steps = append(steps, newPlanStep(migration, DirectionUp, OperationForward))
+	}
+
+	if len(outOfOrder) > 0 && !m.opt.AllowOutOfOrder {
+		return nil, fmt.Errorf("%w: %s are not applied while %d is",
+			errOutOfOrderMigrations, strings.Join(outOfOrder, ", "), lastAppliedMigrationNumber)
+	}
+
+	return steps, nil
+}
+
+// planReadOnly computes the plan without writing anything to the database, assuming an empty history
+// when the pre-migration task would drop or clear it, and reading the legacy table it would move.
+func (m *migrationTask) planReadOnly(ctx context.Context) ([]PlanStep, error) {
+	var applied []*migration
+
+	if !m.refreshesMigrationTable() && !m.clearsMigrationHistory() {
+		var err error
+
+		if applied, err = m.readAppliedMigrations(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.plan(newAppliedSet(applied))
+}
+
+// readAppliedMigrations reads the history without writing anything to the database,
+// refusing dirty and changed migrations the way migrating does.
+func (m *migrationTask) readAppliedMigrations(ctx context.Context) ([]*migration, error) {
+	if m.opt.MoveLegacyTable {
+		applied, found, err := m.repo.GetLegacyAppliedMigrations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get legacy applied migrations: %w", err)
+		}
+
+		if found {
+			return applied, nil
+		}
+	}
+
+	exists, err := m.repo.MigrationTableExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the migrations table: %w", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	applied, err := m.repo.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if _, err = m.dirtyMigrations(applied); err != nil {
+		return nil, err
+	}
+
+	applied = withoutDirtyMigrations(applied)
+
+	if _, err = m.getChecksumMismatches(applied); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// clearsMigrationHistory tells whether the pre-migration task clears the history: kept in a TableSchema
+// of its own, it describes the migrated public schema and starts over when the latter is refreshed.
+func (m *migrationTask) clearsMigrationHistory() bool {
+	return m.opt.TableSchema != "" && !m.refreshesMigrationTable() &&
+		slices.Contains(m.schemasToRefresh(), defaultSchema)
+}
+
+// refreshesMigrationTable tells whether the pre-migration task drops the schema holding the migrations table.
+func (m *migrationTask) refreshesMigrationTable() bool {
+	for _, schemaName := range m.schemasToRefresh() {
+		if schemaName == m.migrationTableSchema() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *migrationTask) dryRun(ctx context.Context) error {
+	steps, err := m.planReadOnly(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to plan migrations: %w", err)
+	}
+
+	if len(steps) == 0 {
+		m.opt.Logger.InfoContext(ctx, "dry run: nothing to apply")
+
+		return nil
+	}
+
+	for _, step := range steps {
+		m.opt.Logger.InfoContext(ctx, "dry run: would apply migration", LogKeyNumber, step.Number, LogKeyName, step.Name,
+			LogKeyDirection, string(step.Direction), "operation", string(step.Operation))
+	}
+
+	return nil
+}
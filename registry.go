@@ -0,0 +1,58 @@
+package migrate
+
+import "io/fs"
+
+// Registry holds a set of migrations, independent of the migrations of other registries.
+// Use it with NewWithRegistry to run several migration sets in a single process.
+type Registry struct {
+	migrations []*Migration
+}
+
+//nolint:gochecknoglobals // default registry backs the package-level AddMigration & New
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty migration registry.
+func NewRegistry() *Registry {
+	return &Registry{migrations: nil}
+}
+
+// Add registers the migration.
+func (r *Registry) Add(m *Migration) {
+	r.migrations = append(r.migrations, m)
+}
+
+// AddFS registers the SQL migrations found in dir of fsys, see LoadMigrationsFS.
+func (r *Registry) AddFS(fsys fs.FS, dir string) error {
+	loaded, err := LoadMigrationsFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range loaded {
+		r.Add(m)
+	}
+
+	return nil
+}
+
+// Migrations returns the registered migrations in the order they were added.
+func (r *Registry) Migrations() []*Migration {
+	return append([]*Migration(nil), r.migrations...)
+}
+
+// Validate fails when the registered migrations are invalid, e.g. on duplicate numbers.
+// NewWithRegistry calls it, so do New and the validate command of CLI.
+func (r *Registry) Validate() error {
+	return validateMigrations(r.migrations)
+}
+
+// AddMigration registers the migration in the default registry used by New.
+func AddMigration(m *Migration) {
+	defaultRegistry.Add(m)
+}
+
+// AddMigrationsFS registers the SQL migrations found in dir of fsys in the default registry used by New,
+// see LoadMigrationsFS.
+func AddMigrationsFS(fsys fs.FS, dir string) error {
+	return defaultRegistry.AddFS(fsys, dir)
+}
@@ -0,0 +1,40 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	migrations := prepareMigrations()
+
+	first, second := NewRegistry(), NewRegistry()
+	first.Add(migrations[0])
+	first.Add(migrations[1])
+	second.Add(migrations[2])
+
+	assert.Equal(t, migrations[:2], first.Migrations())
+	assert.Equal(t, migrations[2:], second.Migrations())
+
+	err := second.AddFS(fstest.MapFS{
+		"sql/0004_add_phone.up.sql": {Data: []byte("ALTER TABLE users ADD COLUMN phone TEXT;")},
+	}, "sql")
+	assert.NoError(t, err)
+
+	if assert.Len(t, second.Migrations(), 2) {
+		assert.Equal(t, uint(4), second.Migrations()[1].Number)
+	}
+
+	assert.Len(t, first.Migrations(), 2, "registries are independent")
+	assert.NoError(t, second.Validate())
+
+	second.Add(migrations[2])
+	assert.ErrorIs(t, second.Validate(), errDuplicateMigrationVersion)
+
+	err = first.AddFS(fstest.MapFS{}, "sql")
+	assert.Error(t, err, "missing directory")
+}
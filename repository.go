@@ -3,69 +3,368 @@ package migrate
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq" // postgres driver
 )
 
 type repository interface {
-	GetLatestMigrationNumber() (uint, error)
-	ApplyMigration(txFunc func(Tx) error) error
-	InsertMigration(m *migration) error
-	RemoveMigrationsAfter(number uint) error
-	EnsureMigrationTable() error
-	DropSchema(schemaName string) error
+	MigrationTableExists(ctx context.Context) (bool, error)
+	GetAppliedMigrations(ctx context.Context) ([]*migration, error)
+	UpdateMigrationChecksum(ctx context.Context, number uint, checksum string) error
+	ApplyMigration(ctx context.Context, m *migration, direction Direction) error
+	ApplyMigrationWithoutTransaction(ctx context.Context, m *migration, direction Direction) error
+	ForceMigration(ctx context.Context, m *migration, direction Direction) error
+	BaselineMigrations(ctx context.Context, migrations []*migration) error
+	RemoveDirtyMigrations(ctx context.Context) error
+	ClearMigrations(ctx context.Context) error
+	EnsureMigrationTable(ctx context.Context) error
+	EnsureAuditTable(ctx context.Context) error
+	MoveLegacyMigrationTable(ctx context.Context) (bool, error)
+	GetLegacyAppliedMigrations(ctx context.Context) ([]*migration, bool, error)
+	DropSchema(ctx context.Context, schemaName string) error
+	Lock(ctx context.Context, key int64) (bool, error)
+	Unlock(ctx context.Context, key int64) error
+	GetLockHolders(ctx context.Context, key int64) ([]lockHolder, error)
+	InsertAuditEvent(ctx context.Context, event *AuditEvent) error
+	GetAuditEvents(ctx context.Context) ([]AuditEvent, error)
+	Close() error
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 type repo struct {
 	db *sql.DB
+
+	// ownsDB is set when the repo opened db itself and has to close it.
+	ownsDB bool
+
+	// tableSchema and tableName locate the migration history table, table is their quoted qualified name.
+	tableSchema string
+	tableName   string
+	table       string
+
+	// auditTable is the quoted qualified name of the audit table, next to the history table.
+	auditTable string
+
+	// appliedBy and appVersion are recorded with every applied migration.
+	appliedBy  string
+	appVersion string
+
+	// conn pins the session of the advisory lock, all queries run on it while it is set.
+	conn   *sql.Conn
+	locked bool
+}
+
+const (
+	defaultTableName = "migrations"
+	auditTableSuffix = "_audit"
+
+	// auditHoldingSchema holds the audit table while the schema it lives in is refreshed.
+	auditHoldingSchema = "go_pg_migrate_audit_holding"
+
+	// tableSchemaQuery looks up the schema of a table resolved the way the search_path does.
+	tableSchemaQuery = `
+		SELECT n.nspname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1)
+	`
+)
+
+var errMultipleDatabases = errors.New("only one of DatabaseURI, DB, Connector and PgxPool can be set")
+
+// nonClosingConnector hides the Close method of a connector provided by the caller,
+// which sql.DB would otherwise call when it is closed.
+type nonClosingConnector struct {
+	driver.Connector
+}
+
+// validateDatabase fails when the options set more than one database to migrate.
+func validateDatabase(opt Options) error {
+	sources := 0
+
+	for _, isSet := range []bool{opt.DatabaseURI != "", opt.DB != nil, opt.Connector != nil, opt.PgxPool != nil} {
+		if isSet {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return errMultipleDatabases
+	}
+
+	return nil
+}
+
+// openDB returns the database to migrate from the options, and whether it was opened here and has to be closed.
+func openDB(opt Options) (*sql.DB, bool, error) {
+	if err := validateDatabase(opt); err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case opt.DB != nil:
+		return opt.DB, false, nil
+	case opt.Connector != nil:
+		return sql.OpenDB(nonClosingConnector{Connector: opt.Connector}), true, nil
+	case opt.PgxPool != nil:
+		// The database is closed on its own, the pool is left open.
+		return stdlib.OpenDBFromPool(opt.PgxPool), true, nil
+	}
+
+	driverName, err := sqlDriverName(opt.Driver)
+	if err != nil {
+		return nil, false, err
+	}
+
+	db, err := sql.Open(driverName, opt.DatabaseURI)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return db, true, nil
+}
+
+func newRepo(db *sql.DB, ownsDB bool, opt Options) *repo {
+	tableSchema, tableName := opt.TableSchema, opt.TableName
+	if tableName == "" {
+		tableName = defaultTableName
+	}
+
+	table, auditTable := quoteIdentifier(tableName), quoteIdentifier(tableName+auditTableSuffix)
+	if tableSchema != "" {
+		table = quoteIdentifier(tableSchema) + "." + table
+		auditTable = quoteIdentifier(tableSchema) + "." + auditTable
+	}
+
+	//nolint:exhaustivestruct,exhaustruct // conn is pinned on Lock
+	return &repo{
+		db: db, ownsDB: ownsDB, tableSchema: tableSchema, tableName: tableName, table: table, auditTable: auditTable,
+		appliedBy: opt.AppliedBy, appVersion: opt.AppVersion,
+	}
+}
+
+// quoteIdentifier quotes a PostgreSQL identifier such as a schema or table name.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
 
-func newRepo(databaseURI string) (*repo, error) {
-	db, err := sql.Open("postgres", databaseURI)
+func (r *repo) querier() querier {
+	if r.conn != nil {
+		return r.conn
+	}
+
+	return r.db
+}
+
+func (r *repo) MigrationTableExists(ctx context.Context) (bool, error) {
+	var exists bool
+
+	const query = "SELECT to_regclass($1) IS NOT NULL"
+
+	err := r.querier().QueryRowContext(ctx, query, r.table).Scan(&exists)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return false, fmt.Errorf("failed to check migrations table: %w", err)
 	}
 
-	return &repo{db}, nil
+	return exists, nil
 }
 
-// GetLatestMigrationNumber returns 0,nil if not found.
-func (r *repo) GetLatestMigrationNumber() (uint, error) {
-	var latestMigrationNumber uint
+// GetAppliedMigrations returns the recorded migrations ordered by number.
+func (r *repo) GetAppliedMigrations(ctx context.Context) ([]*migration, error) {
+	query := fmt.Sprintf(
+		"SELECT id, created_at, number, name, checksum, no_transaction, dirty, baselined FROM %s ORDER BY number",
+		r.table)
+
+	rows, err := r.querier().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []*migration
 
-	const query = "SELECT number FROM migrations ORDER BY number DESC LIMIT 1"
+	for rows.Next() {
+		//nolint:exhaustivestruct,exhaustruct // funcs are not stored
+		m := &migration{}
 
-	err := r.db.QueryRowContext(context.TODO(), query).
-		Scan(&latestMigrationNumber)
+		err = rows.Scan(&m.ID, &m.CreatedAt, &m.Number, &m.Name, &m.Checksum, &m.NoTransaction, &m.Dirty, &m.Baselined)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+
+		applied = append(applied, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (r *repo) UpdateMigrationChecksum(ctx context.Context, number uint, checksum string) error {
+	query := fmt.Sprintf("UPDATE %s SET checksum = $2 WHERE number = $1", r.table)
+
+	_, err := r.querier().ExecContext(ctx, query, number, checksum)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, nil
+		return fmt.Errorf("failed to update migration checksum: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyMigration runs the migration in the given direction in a transaction bound to ctx,
+// recording it in the same transaction. Once ctx is done the transaction is rolled back.
+func (r *repo) ApplyMigration(ctx context.Context, m *migration, direction Direction) error {
+	return r.inTransaction(ctx, func(dbTransaction *sql.Tx) error {
+		if direction == DirectionDown {
+			//nolint:exhaustivestruct,exhaustruct // Conn is set only without a transaction
+			if err := m.Backwards(Tx{Tx: dbTransaction, raw: r.conn, ctx: ctx}); err != nil {
+				return err
+			}
+
+			return r.removeMigrationsAfter(ctx, dbTransaction, m.Number)
+		}
+
+		started := time.Now()
+
+		//nolint:exhaustivestruct,exhaustruct // Conn is set only without a transaction
+		if err := m.Forwards(Tx{Tx: dbTransaction, raw: r.conn, ctx: ctx}); err != nil {
+			return err
+		}
+
+		//nolint:exhaustivestruct,exhaustruct // not dirty
+		return r.insertMigration(ctx, dbTransaction, m, migrationRun{direction: direction, duration: time.Since(started)})
+	})
+}
+
+// ApplyMigrationWithoutTransaction runs the migration in the given direction on a single connection,
+// the pinned one when there is one. The migration is recorded as dirty while it runs,
+// so a failure leaves a dirty record behind.
+func (r *repo) ApplyMigrationWithoutTransaction(ctx context.Context, m *migration, direction Direction) error {
+	conn := r.conn
+
+	if conn == nil {
+		var err error
+
+		if conn, err = r.db.Conn(ctx); err != nil {
+			return fmt.Errorf("failed to get connection: %w", err)
 		}
 
-		return 0, fmt.Errorf("failed to get latest migration number: %w", err)
+		defer conn.Close()
 	}
 
-	return latestMigrationNumber, nil
+	txFunc := m.Forwards
+	started := time.Now()
+	markDirty := func() error {
+		//nolint:exhaustivestruct,exhaustruct // the duration is recorded once done
+		return r.insertMigration(ctx, conn, m, migrationRun{direction: direction, dirty: true})
+	}
+	record := func(ctx context.Context) error { return r.finishMigration(ctx, conn, m.Number, time.Since(started)) }
+
+	if direction == DirectionDown {
+		txFunc = m.Backwards
+		markDirty = func() error { return r.markMigrationDirty(ctx, conn, m.Number, direction) }
+		record = func(ctx context.Context) error { return r.removeMigrationsAfter(ctx, conn, m.Number) }
+	}
+
+	if err := markDirty(); err != nil {
+		return err
+	}
+
+	//nolint:exhaustivestruct,exhaustruct // no transaction
+	if err := txFunc(Tx{Conn: conn, raw: conn, ctx: ctx}); err != nil {
+		return fmt.Errorf("failed to apply the migration (no transaction to roll back, recorded as dirty): %w", err)
+	}
+
+	// The migration is done at this point, so record it even if ctx got cancelled meanwhile.
+	return record(context.WithoutCancel(ctx))
 }
 
-func (r *repo) ApplyMigration(txFunc func(Tx) error) error {
-	dbTransaction, err := r.db.Begin()
+// ForceMigration records the migration as the latest applied one, removing the records from its number on.
+// The direction tells whether the version was forced up or down.
+func (r *repo) ForceMigration(ctx context.Context, m *migration, direction Direction) error {
+	return r.inTransaction(ctx, func(dbTransaction *sql.Tx) error {
+		if err := r.removeMigrationsAfter(ctx, dbTransaction, m.Number); err != nil {
+			return err
+		}
+
+		//nolint:exhaustivestruct,exhaustruct // forced migrations do not run
+		return r.insertMigration(ctx, dbTransaction, m, migrationRun{direction: direction})
+	})
+}
+
+// BaselineMigrations records the migrations as applied without running them, in a single transaction.
+func (r *repo) BaselineMigrations(ctx context.Context, migrations []*migration) error {
+	return r.inTransaction(ctx, func(dbTransaction *sql.Tx) error {
+		for _, m := range migrations {
+			//nolint:exhaustivestruct,exhaustruct // baselined migrations do not run
+			run := migrationRun{direction: DirectionUp, baselined: true}
+
+			if err := r.insertMigration(ctx, dbTransaction, m, run); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveDirtyMigrations removes the records of migrations that failed without a transaction.
+func (r *repo) RemoveDirtyMigrations(ctx context.Context) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE dirty", r.table)
+
+	_, err := r.querier().ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to delete dirty migrations: %w", err)
+	}
+
+	return nil
+}
+
+// ClearMigrations removes every record of the migration history.
+func (r *repo) ClearMigrations(ctx context.Context) error {
+	_, err := r.querier().ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", r.table))
+	if err != nil {
+		return fmt.Errorf("failed to clear migrations: %w", err)
+	}
+
+	return nil
+}
+
+// inTransaction runs fn in a transaction bound to ctx, committing it when fn succeeds.
+func (r *repo) inTransaction(ctx context.Context, fn func(dbTransaction *sql.Tx) error) error {
+	dbTransaction, err := r.querier().BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("starting transaction: %w", err)
 	}
 
-	if err = txFunc(Tx{dbTransaction}); err != nil {
-		if rollbackErr := dbTransaction.Rollback(); rollbackErr != nil {
+	if err = fn(dbTransaction); err != nil {
+		if rollbackErr := dbTransaction.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
 			return fmt.Errorf("failed to rollback after failed transaction: %w", rollbackErr)
 		}
 
-		return fmt.Errorf("failed to apply the migration (rolled back successfully though): %w", err)
+		return fmt.Errorf("failed in transaction (rolled back successfully though): %w", err)
 	}
 
 	if err = dbTransaction.Commit(); err != nil {
-		if rollbackErr := dbTransaction.Rollback(); rollbackErr != nil {
+		if rollbackErr := dbTransaction.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
 			return fmt.Errorf("failed to rollback after failed commit: %w", rollbackErr)
 		}
 
@@ -75,10 +374,23 @@ func (r *repo) ApplyMigration(txFunc func(Tx) error) error {
 	return nil
 }
 
-func (r *repo) InsertMigration(m *migration) error {
-	const query = "INSERT INTO migrations (number, name) VALUES ($1, $2)"
+// migrationRun describes how a migration recorded in the history table was run.
+type migrationRun struct {
+	direction Direction
+	duration  time.Duration
+	dirty     bool
+	baselined bool
+}
+
+func (r *repo) insertMigration(ctx context.Context, db execer, m *migration, run migrationRun) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (
+			number, name, checksum, no_transaction, dirty, duration_ms, applied_by, app_version, direction, baselined
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`, r.table)
 
-	_, err := r.db.ExecContext(context.TODO(), query, m.Number, m.Name)
+	_, err := db.ExecContext(ctx, query, m.Number, m.Name, m.Checksum, m.NoTransaction, run.dirty,
+		run.duration.Milliseconds(), r.appliedBy, r.appVersion, string(run.direction), run.baselined)
 	if err != nil {
 		return fmt.Errorf("failed to create migration record: %w", err)
 	}
@@ -86,10 +398,35 @@ func (r *repo) InsertMigration(m *migration) error {
 	return nil
 }
 
-func (r *repo) RemoveMigrationsAfter(number uint) error {
-	const query = "DELETE FROM migrations WHERE number >= $1"
+// finishMigration clears the dirty state of a migration run without a transaction, recording how long it took.
+func (r *repo) finishMigration(ctx context.Context, db execer, number uint, duration time.Duration) error {
+	query := fmt.Sprintf("UPDATE %s SET dirty = FALSE, duration_ms = $2 WHERE number = $1", r.table)
+
+	_, err := db.ExecContext(ctx, query, number, duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to update migration record: %w", err)
+	}
+
+	return nil
+}
+
+// markMigrationDirty marks the record of a migration about to run without a transaction as dirty,
+// along with the direction it runs in, so that a failure tells which direction left the database dirty.
+func (r *repo) markMigrationDirty(ctx context.Context, db execer, number uint, direction Direction) error {
+	query := fmt.Sprintf("UPDATE %s SET dirty = TRUE, direction = $2 WHERE number = $1", r.table)
+
+	_, err := db.ExecContext(ctx, query, number, string(direction))
+	if err != nil {
+		return fmt.Errorf("failed to update migration record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repo) removeMigrationsAfter(ctx context.Context, db execer, number uint) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE number >= $1", r.table)
 
-	_, err := r.db.ExecContext(context.TODO(), query, number)
+	_, err := db.ExecContext(ctx, query, number)
 	if err != nil {
 		return fmt.Errorf("failed to delete migrations: %w", err)
 	}
@@ -97,30 +434,486 @@ func (r *repo) RemoveMigrationsAfter(number uint) error {
 	return nil
 }
 
-func (r *repo) EnsureMigrationTable() error {
-	const query = `
-		CREATE TABLE IF NOT EXISTS migrations (
+func (r *repo) EnsureMigrationTable(ctx context.Context) error {
+	if err := r.ensureTableSchema(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
 			id SERIAL PRIMARY KEY,
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			number INTEGER NOT NULL UNIQUE,
+			number BIGINT NOT NULL UNIQUE,
 			name VARCHAR(255) NOT NULL
 		)
-	`
+	`, r.table)
 
-	_, err := r.db.ExecContext(context.TODO(), query)
+	_, err := r.querier().ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to ensure migration table: %w", err)
 	}
 
+	// Bring tables created by older versions up to date.
+	upgrades := []string{
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS no_transaction BOOLEAN NOT NULL DEFAULT FALSE",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS dirty BOOLEAN NOT NULL DEFAULT FALSE",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS duration_ms BIGINT NOT NULL DEFAULT 0",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied_by TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS app_version TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS direction TEXT NOT NULL DEFAULT 'up'",
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS baselined BOOLEAN NOT NULL DEFAULT FALSE",
+	}
+
+	for _, upgrade := range upgrades {
+		if _, err = r.querier().ExecContext(ctx, fmt.Sprintf(upgrade, r.table)); err != nil {
+			return fmt.Errorf("failed to upgrade migration table: %w", err)
+		}
+	}
+
+	if err = r.upgradeNumberColumn(ctx); err != nil {
+		return err
+	}
+
+	return r.ensureAuditTable(ctx)
+}
+
+// upgradeNumberColumn widens the INTEGER number column of tables created by older versions to BIGINT,
+// so that timestamp versions fit. The table is rewritten only when the column is not BIGINT yet.
+func (r *repo) upgradeNumberColumn(ctx context.Context) error {
+	const query = `
+		SELECT format_type(atttypid, atttypmod) FROM pg_attribute
+		WHERE attrelid = to_regclass($1) AND attname = 'number' AND NOT attisdropped`
+
+	var columnType string
+
+	if err := r.querier().QueryRowContext(ctx, query, r.table).Scan(&columnType); err != nil {
+		return fmt.Errorf("failed to check migration number column: %w", err)
+	}
+
+	if columnType == "bigint" {
+		return nil
+	}
+
+	upgrade := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN number TYPE BIGINT", r.table)
+
+	if _, err := r.querier().ExecContext(ctx, upgrade); err != nil {
+		return fmt.Errorf("failed to upgrade migration number column: %w", err)
+	}
+
+	return nil
+}
+
+// EnsureAuditTable creates the audit table when missing, leaving the history table alone.
+func (r *repo) EnsureAuditTable(ctx context.Context) error {
+	if err := r.ensureTableSchema(ctx); err != nil {
+		return err
+	}
+
+	return r.ensureAuditTable(ctx)
+}
+
+func (r *repo) ensureTableSchema(ctx context.Context) error {
+	if r.tableSchema == "" {
+		return nil
+	}
+
+	_, err := r.querier().ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+quoteIdentifier(r.tableSchema))
+	if err != nil {
+		return fmt.Errorf("failed to ensure migration table schema: %w", err)
+	}
+
 	return nil
 }
 
-func (r *repo) DropSchema(schemaName string) error {
-	_, err := r.db.ExecContext(context.TODO(),
-		fmt.Sprintf(`DROP SCHEMA IF EXISTS %q CASCADE; CREATE SCHEMA IF NOT EXISTS %q;`,
-			schemaName, schemaName))
+func (r *repo) ensureAuditTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			action TEXT NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			app_version TEXT NOT NULL DEFAULT '',
+			from_version BIGINT NOT NULL DEFAULT 0,
+			to_version BIGINT NOT NULL DEFAULT 0,
+			number BIGINT NOT NULL DEFAULT 0,
+			name TEXT NOT NULL DEFAULT '',
+			outcome TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`, r.auditTable)
+
+	if _, err := r.querier().ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to ensure audit table: %w", err)
+	}
+
+	return nil
+}
+
+// InsertAuditEvent appends the event to the audit table, rows of which are never updated nor deleted.
+func (r *repo) InsertAuditEvent(ctx context.Context, event *AuditEvent) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (action, actor, app_version, from_version, to_version, number, name, outcome, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`, r.auditTable)
+
+	_, err := r.querier().ExecContext(ctx, query, string(event.Action), event.Actor, event.AppVersion,
+		event.FromVersion, event.ToVersion, event.Number, event.Name, string(event.Outcome), event.Error,
+		event.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditEvents returns the audit events in the order they were inserted, none when there is no audit table yet.
+func (r *repo) GetAuditEvents(ctx context.Context) ([]AuditEvent, error) {
+	var exists bool
+
+	err := r.querier().QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", r.auditTable).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audit table: %w", err)
+	}
+
+	if !exists {
+		return []AuditEvent{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, created_at, action, actor, app_version, from_version, to_version, number, name, outcome, error,
+			duration_ms
+		FROM %s ORDER BY id`, r.auditTable)
+
+	rows, err := r.querier().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+
+	for rows.Next() {
+		var (
+			event      AuditEvent
+			durationMs int64
+		)
+
+		err = rows.Scan(&event.ID, &event.CreatedAt, &event.Action, &event.Actor, &event.AppVersion,
+			&event.FromVersion, &event.ToVersion, &event.Number, &event.Name, &event.Outcome, &event.Error, &durationMs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+
+		event.Duration = time.Duration(durationMs) * time.Millisecond
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// MoveLegacyMigrationTable moves the default migrations table found on the search_path to the configured location,
+// unless the configured table exists already. Reports whether the table was moved.
+func (r *repo) MoveLegacyMigrationTable(ctx context.Context) (bool, error) {
+	if r.table == quoteIdentifier(defaultTableName) {
+		return false, nil
+	}
+
+	var moved bool
+
+	err := r.inTransaction(ctx, func(dbTransaction *sql.Tx) error {
+		var moveErr error
+
+		moved, moveErr = r.moveLegacyMigrationTable(ctx, dbTransaction)
+
+		return moveErr
+	})
+
+	return moved, err
+}
+
+// GetLegacyAppliedMigrations returns the migrations recorded in the legacy table MoveLegacyMigrationTable would move,
+// reporting whether it would move one. Only the columns of tables created by older versions are read.
+func (r *repo) GetLegacyAppliedMigrations(ctx context.Context) ([]*migration, bool, error) {
+	if r.table == quoteIdentifier(defaultTableName) {
+		return nil, false, nil
+	}
+
+	var targetExists, legacyExists bool
+
+	err := r.querier().QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL, to_regclass($2) IS NOT NULL",
+		r.table, quoteIdentifier(defaultTableName)).Scan(&targetExists, &legacyExists)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up legacy migrations table: %w", err)
+	}
+
+	if targetExists || !legacyExists {
+		return nil, false, nil
+	}
+
+	query := "SELECT id, created_at, number, name FROM " + quoteIdentifier(defaultTableName) + " ORDER BY number"
+
+	rows, err := r.querier().QueryContext(ctx, query)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query legacy applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []*migration
+
+	for rows.Next() {
+		//nolint:exhaustivestruct,exhaustruct // funcs are not stored
+		m := &migration{}
+
+		if err = rows.Scan(&m.ID, &m.CreatedAt, &m.Number, &m.Name); err != nil {
+			return nil, false, fmt.Errorf("failed to scan legacy applied migration: %w", err)
+		}
+
+		applied = append(applied, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read legacy applied migrations: %w", err)
+	}
+
+	return applied, true, nil
+}
+
+func (r *repo) moveLegacyMigrationTable(ctx context.Context, dbTransaction *sql.Tx) (bool, error) {
+	var targetExists bool
+
+	err := dbTransaction.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", r.table).Scan(&targetExists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+
+	if targetExists {
+		return false, nil
+	}
+
+	var legacySchema string
+
+	err = dbTransaction.QueryRowContext(ctx, tableSchemaQuery, quoteIdentifier(defaultTableName)).Scan(&legacySchema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to look up legacy migrations table: %w", err)
+	}
+
+	if err = r.moveLegacyTable(ctx, dbTransaction, legacySchema, defaultTableName, r.tableName); err != nil {
+		return false, err
+	}
+
+	// The audit table follows its history table, unless one is already next to the configured table.
+	legacyAudit := quoteIdentifier(legacySchema) + "." + quoteIdentifier(defaultTableName+auditTableSuffix)
+
+	var legacyAuditExists, auditExists bool
+
+	err = dbTransaction.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL, to_regclass($2) IS NOT NULL",
+		legacyAudit, r.auditTable).Scan(&legacyAuditExists, &auditExists)
 	if err != nil {
-		return fmt.Errorf("failed to drop schema: %w", err)
+		return false, fmt.Errorf("failed to check audit table: %w", err)
+	}
+
+	if legacyAuditExists && !auditExists {
+		err = r.moveLegacyTable(ctx, dbTransaction, legacySchema,
+			defaultTableName+auditTableSuffix, r.tableName+auditTableSuffix)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// moveLegacyTable moves the table from the legacy schema to the configured one, renaming it to tableName.
+func (r *repo) moveLegacyTable(
+	ctx context.Context, dbTransaction *sql.Tx, legacySchema, legacyName, tableName string,
+) error {
+	schema := legacySchema
+
+	if r.tableSchema != "" && r.tableSchema != legacySchema {
+		schema = r.tableSchema
+
+		statements := []string{
+			"CREATE SCHEMA IF NOT EXISTS " + quoteIdentifier(schema),
+			fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA %s",
+				quoteIdentifier(legacySchema), quoteIdentifier(legacyName), quoteIdentifier(schema)),
+		}
+
+		for _, statement := range statements {
+			if _, err := dbTransaction.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to move legacy table %s: %w", legacyName, err)
+			}
+		}
+	}
+
+	if tableName != legacyName {
+		statement := fmt.Sprintf("ALTER TABLE %s.%s RENAME TO %s",
+			quoteIdentifier(schema), quoteIdentifier(legacyName), quoteIdentifier(tableName))
+
+		if _, err := dbTransaction.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to rename legacy table %s: %w", legacyName, err)
+		}
+	}
+
+	return nil
+}
+
+// DropSchema drops & recreates the schema, keeping the audit table when it lives there:
+// it is set aside in a holding schema and moved back, all in one transaction.
+func (r *repo) DropSchema(ctx context.Context, schemaName string) error {
+	return r.inTransaction(ctx, func(dbTransaction *sql.Tx) error {
+		keepsAudit, err := r.auditTableIn(ctx, dbTransaction, schemaName)
+		if err != nil {
+			return err
+		}
+
+		schema, auditName := quoteIdentifier(schemaName), quoteIdentifier(r.tableName+auditTableSuffix)
+
+		statements := []string{
+			fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA IF NOT EXISTS %s;`, schema, schema),
+		}
+
+		if keepsAudit {
+			holding := quoteIdentifier(auditHoldingSchema)
+			statements = []string{
+				"CREATE SCHEMA " + holding,
+				fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA %s", schema, auditName, holding),
+				statements[0],
+				fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA %s", holding, auditName, schema),
+				"DROP SCHEMA " + holding,
+			}
+		}
+
+		for _, statement := range statements {
+			if _, err = dbTransaction.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to drop schema: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// auditTableIn tells whether the audit table exists in the schema.
+func (r *repo) auditTableIn(ctx context.Context, dbTransaction *sql.Tx, schemaName string) (bool, error) {
+	var auditSchema sql.NullString
+
+	err := dbTransaction.QueryRowContext(ctx, tableSchemaQuery, r.auditTable).Scan(&auditSchema)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to look up audit table: %w", err)
+	}
+
+	return auditSchema.Valid && auditSchema.String == schemaName, nil
+}
+
+// Lock tries to take the session-level advisory lock without waiting, pinning the connection it runs on.
+func (r *repo) Lock(ctx context.Context, key int64) (bool, error) {
+	if r.conn == nil {
+		conn, err := r.db.Conn(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get connection: %w", err)
+		}
+
+		r.conn = conn
+	}
+
+	const query = "SELECT pg_try_advisory_lock($1)"
+
+	err := r.conn.QueryRowContext(ctx, query, key).Scan(&r.locked)
+	if err != nil {
+		return false, fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+
+	return r.locked, nil
+}
+
+// Unlock releases the advisory lock if it is held and unpins the connection.
+func (r *repo) Unlock(ctx context.Context, key int64) error {
+	if r.conn == nil {
+		return nil
+	}
+
+	conn, locked := r.conn, r.locked
+	r.conn, r.locked = nil, false
+
+	if locked {
+		const query = "SELECT pg_advisory_unlock($1)"
+
+		var unlocked bool
+
+		if err := conn.QueryRowContext(ctx, query, key).Scan(&unlocked); err != nil {
+			// Discard the session rather than returning it to the pool while it may still hold the lock.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			_ = conn.Close()
+
+			return fmt.Errorf("failed to release advisory lock: %w", err)
+		}
+
+		if !unlocked {
+			_ = conn.Close()
+
+			return fmt.Errorf("failed to release advisory lock %d: %w", key, errLockNotHeld)
+		}
+	}
+
+	if err := conn.Close(); err != nil {
+		return fmt.Errorf("failed to close connection: %w", err)
+	}
+
+	return nil
+}
+
+// GetLockHolders lists the sessions holding the advisory lock.
+func (r *repo) GetLockHolders(ctx context.Context, key int64) ([]lockHolder, error) {
+	const query = `
+		SELECT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), '')
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+			AND ((l.classid::BIGINT << 32) | l.objid::BIGINT) = $1
+	`
+
+	rows, err := r.querier().QueryContext(ctx, query, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lock holders: %w", err)
+	}
+	defer rows.Close()
+
+	var holders []lockHolder
+
+	for rows.Next() {
+		var holder lockHolder
+
+		if err = rows.Scan(&holder.PID, &holder.User, &holder.ApplicationName, &holder.ClientAddr); err != nil {
+			return nil, fmt.Errorf("failed to scan lock holder: %w", err)
+		}
+
+		holders = append(holders, holder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lock holders: %w", err)
+	}
+
+	return holders, nil
+}
+
+// Close closes the database when the repo opened it, a database provided by the caller is left open.
+func (r *repo) Close() error {
+	if !r.ownsDB {
+		return nil
+	}
+
+	if err := r.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,120 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedExec is a statement executed on a recordingConn, with its arguments.
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+// recordingConnector opens connections recording the statements executed on them, without a database.
+type recordingConnector struct {
+	execs []recordedExec
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+// find returns the executed statements starting with prefix, ignoring leading whitespace.
+func (c *recordingConnector) find(prefix string) []recordedExec {
+	var found []recordedExec
+
+	for _, exec := range c.execs {
+		if strings.HasPrefix(strings.TrimSpace(exec.query), prefix) {
+			found = append(found, exec)
+		}
+	}
+
+	return found
+}
+
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+var errTestNotSupported = errors.New("test-not-supported")
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) { return nil, errTestNotSupported }
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *recordingConn) Commit() error { return nil }
+
+func (c *recordingConn) Rollback() error { return nil }
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	c.connector.execs = append(c.connector.execs, recordedExec{query: query, args: values})
+
+	return driver.RowsAffected(1), nil
+}
+
+func TestRepoRecordsRuns(t *testing.T) {
+	t.Parallel()
+
+	errMigration := errors.New("test-migration-err") //nolint:goerr113 // used for tests only
+
+	connector := new(recordingConnector)
+	r := newRepo(sql.OpenDB(connector), true, Options{AppliedBy: "ci", AppVersion: "v1"})
+
+	defer r.Close()
+
+	//nolint:exhaustivestruct,exhaustruct // funcs set below
+	m := &migration{Number: 3, Name: "Add Phone", NoTransaction: true}
+	m.Forwards = func(Tx) error {
+		time.Sleep(5 * time.Millisecond)
+
+		return nil
+	}
+	m.Backwards = func(Tx) error { return errMigration }
+
+	ctx := context.Background()
+
+	assert.NoError(t, r.ApplyMigration(ctx, m, DirectionUp))
+	assert.NoError(t, r.ForceMigration(ctx, m, DirectionDown))
+	assert.NoError(t, r.ApplyMigrationWithoutTransaction(ctx, m, DirectionUp))
+	assert.ErrorIs(t, r.ApplyMigrationWithoutTransaction(ctx, m, DirectionDown), errMigration)
+
+	inserts := connector.find("INSERT INTO")
+	if assert.Len(t, inserts, 3) {
+		// number, name, checksum, no_transaction, dirty, duration_ms, applied_by, app_version, direction, baselined
+		assert.Equal(t, []driver.Value{"ci", "v1", "up", false}, inserts[0].args[6:], "applied")
+		assert.GreaterOrEqual(t, inserts[0].args[5], int64(5), "applied duration")
+
+		assert.Equal(t, []driver.Value{"ci", "v1", "down", false}, inserts[1].args[6:], "forced down")
+
+		assert.Equal(t, true, inserts[2].args[4], "dirty while running without a transaction")
+		assert.Equal(t, "up", inserts[2].args[8])
+	}
+
+	updates := connector.find("UPDATE")
+	if assert.Len(t, updates, 2) {
+		assert.Contains(t, updates[0].query, "dirty = FALSE, duration_ms = $2", "finished without a transaction")
+		assert.GreaterOrEqual(t, updates[0].args[1], int64(5), "finished duration")
+
+		assert.Contains(t, updates[1].query, "dirty = TRUE, direction = $2", "failing down")
+		assert.Equal(t, []driver.Value{int64(3), "down"}, updates[1].args, "failing down")
+	}
+
+	assert.Len(t, connector.find("DELETE"), 1, "only the forced version removes records")
+}
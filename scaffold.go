@@ -0,0 +1,301 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// MigrationFileType is the kind of migration files CreateMigration writes.
+type MigrationFileType string
+
+const (
+	// MigrationFileSQL is a pair of <number>_<name>.up.sql & <number>_<name>.down.sql files, see LoadMigrationsFS.
+	MigrationFileSQL MigrationFileType = "sql"
+	// MigrationFileGo is a <number>_<name>.go file registering the migration with AddMigration in its init.
+	MigrationFileGo MigrationFileType = "go"
+)
+
+// CreateOptions define the migration CreateMigration writes.
+type CreateOptions struct {
+	// Dir is the directory to write the files to, created when missing.
+	Dir string
+
+	// Name describes the migration, e.g. "add phone for users".
+	Name string
+
+	// Type is the kind of files to write. Defaults to MigrationFileSQL.
+	Type MigrationFileType
+
+	// Timestamp numbers the migration with TimestampVersion instead of the next number after the highest one in Dir.
+	Timestamp bool
+
+	// Package is the package of a Go migration.
+	// Defaults to the package of the other Go files in Dir, or to the name of Dir.
+	Package string
+
+	// Now is the time of a timestamp version. Defaults to the current time.
+	Now time.Time
+}
+
+var (
+	errMigrationFileExists = errors.New("migration file exists already")
+	errUnknownFileType     = errors.New("unknown migration file type")
+)
+
+const (
+	sqlFileNumberFormat = "%04d"
+	goFileNumberFormat  = "%d"
+
+	defaultGoPackage = "migrations"
+
+	createdFilePermissions = 0o644
+	createdDirPermissions  = 0o755
+)
+
+var (
+	numberedFileNamePattern = regexp.MustCompile(`^(\d+)_\w+(\.up\.sql|\.down\.sql|\.go)$`)
+	nonWordPattern          = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// CreateMigration writes the files of a new migration to CreateOptions.Dir and returns their paths.
+// The migration is numbered after the highest numbered migration file in the directory, or with a timestamp.
+// It refuses to overwrite existing files.
+func CreateMigration(opt CreateOptions) ([]string, error) {
+	fileName := migrationFileName(opt.Name)
+	if fileName == "" {
+		return nil, errMigrationNameCannotBeEmpty
+	}
+
+	if err := os.MkdirAll(opt.Dir, createdDirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory %s: %w", opt.Dir, err)
+	}
+
+	number, err := nextMigrationNumber(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := scaffoldMigrationFiles(opt, number, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for _, file := range files {
+		filePath := filepath.Join(opt.Dir, file.name)
+
+		if _, err = os.Stat(filePath); !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", filePath, errMigrationFileExists)
+		}
+
+		paths = append(paths, filePath)
+	}
+
+	for i, file := range files {
+		if err = writeNewFile(paths[i], file.content); err != nil {
+			return paths[:i], err
+		}
+	}
+
+	return paths, nil
+}
+
+type scaffoldedFile struct {
+	name    string
+	content []byte
+}
+
+func scaffoldMigrationFiles(opt CreateOptions, number uint, fileName string) ([]scaffoldedFile, error) {
+	switch opt.Type {
+	case "", MigrationFileSQL:
+		prefix := fmt.Sprintf(sqlFileNumberFormat+"_%s", number, fileName)
+		comment := []byte("-- " + strings.ReplaceAll(fileName, "_", " ") + "\n")
+
+		return []scaffoldedFile{
+			{name: prefix + ".up.sql", content: comment},
+			{name: prefix + ".down.sql", content: comment},
+		}, nil
+	case MigrationFileGo:
+		packageName := opt.Package
+		if packageName == "" {
+			packageName = goPackageName(opt.Dir)
+		}
+
+		var content bytes.Buffer
+
+		err := goMigrationTemplate.Execute(&content, map[string]any{
+			"Package": packageName,
+			"Name":    migrationTitle(fileName),
+			"Summary": strings.ReplaceAll(fileName, "_", " "),
+			"Number":  number,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render Go migration: %w", err)
+		}
+
+		return []scaffoldedFile{
+			{name: fmt.Sprintf(goFileNumberFormat+"_%s.go", number, fileName), content: content.Bytes()},
+		}, nil
+	default:
+		return nil, fmt.Errorf("%q: %w", opt.Type, errUnknownFileType)
+	}
+}
+
+// nextMigrationNumber returns the timestamp version when requested, or the number after the highest one in the
+// directory. A timestamp version is still moved past the highest number, so that the new migration sorts last.
+func nextMigrationNumber(opt CreateOptions) (uint, error) {
+	entries, err := os.ReadDir(opt.Dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations directory %s: %w", opt.Dir, err)
+	}
+
+	var highest uint
+
+	for _, entry := range entries {
+		match := numberedFileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		number, parseErr := strconv.ParseUint(match[1], 10, strconv.IntSize)
+		if errors.Is(parseErr, strconv.ErrRange) {
+			return 0, fmt.Errorf("%s: %w: %s", entry.Name(), errVersionOverflow, match[1])
+		}
+
+		if parseErr != nil {
+			return 0, fmt.Errorf("%s: %w", entry.Name(), errMalformedMigrationFileName)
+		}
+
+		if uint(number) > highest {
+			highest = uint(number)
+		}
+	}
+
+	if !opt.Timestamp {
+		return highest + 1, nil
+	}
+
+	now := opt.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	version, err := TimestampVersion(now)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > highest {
+		return version, nil
+	}
+
+	return highest + 1, nil
+}
+
+// migrationFileName turns a migration name into the lower snake case of file names, e.g. add_phone_for_users.
+func migrationFileName(name string) string {
+	return strings.Trim(nonWordPattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// migrationTitle turns a file name into the title case of migration names, e.g. Add Phone For Users.
+func migrationTitle(fileName string) string {
+	words := strings.Split(fileName, "_")
+
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// goPackageName returns the package of the Go files in dir, or the name of dir when it is a valid package name.
+func goPackageName(dir string) string {
+	goFiles, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+
+	for _, goFile := range goFiles {
+		parsed, err := parser.ParseFile(token.NewFileSet(), goFile, nil, parser.PackageClauseOnly)
+		if err == nil && !strings.HasSuffix(goFile, "_test.go") {
+			return parsed.Name.Name
+		}
+	}
+
+	dirName := filepath.Base(dir)
+	if token.IsIdentifier(dirName) && dirName == strings.ToLower(dirName) {
+		return dirName
+	}
+
+	return defaultGoPackage
+}
+
+func writeNewFile(filePath string, content []byte) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, createdFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create migration file: %w", err)
+	}
+
+	if _, err = file.Write(content); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("failed to write migration file %s: %w", filePath, err)
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close migration file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+var goMigrationTemplate = template.Must(template.New("migration").Parse(`package {{ .Package }}
+
+import (
+	"fmt"
+
+	migrate "github.com/lawzava/go-pg-migrate/v2"
+)
+
+func init() {
+	const (
+		up   = ` + "``" + `
+		down = ` + "``" + `
+	)
+
+	migrate.AddMigration(
+		&migrate.Migration{
+			Name:   {{ printf "%q" .Name }},
+			Number: {{ .Number }},
+			Up: func(tx migrate.Tx) error {
+				_, err := tx.ExecContext(tx.Context(), up)
+				if err != nil {
+					return fmt.Errorf("failed to {{ .Summary }}: %w", err)
+				}
+
+				return nil
+			},
+			Down: func(tx migrate.Tx) error {
+				_, err := tx.ExecContext(tx.Context(), down)
+				if err != nil {
+					return fmt.Errorf("failed to revert {{ .Summary }}: %w", err)
+				}
+
+				return nil
+			},
+		},
+	)
+}
+`))
@@ -0,0 +1,113 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMigration(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0004_add_phone_for_users.up.sql"), []byte("SELECT 1"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o600))
+
+	paths, err := CreateMigration(CreateOptions{Dir: dir, Name: "Add index on users' phone"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0005_add_index_on_users_phone.up.sql"),
+		filepath.Join(dir, "0005_add_index_on_users_phone.down.sql"),
+	}, paths)
+
+	loaded, err := LoadMigrationsFS(os.DirFS(dir), ".")
+	assert.NoError(t, err)
+
+	if assert.Len(t, loaded, 2) {
+		assert.Equal(t, uint(5), loaded[1].Number)
+		assert.Equal(t, "add index on users phone", loaded[1].Name)
+		assert.NotNil(t, loaded[1].Down)
+	}
+
+	_, err = CreateMigration(CreateOptions{Dir: dir, Name: "add_index_on_users_phone", Type: MigrationFileGo})
+	assert.NoError(t, err, "numbered after the SQL files")
+	assert.FileExists(t, filepath.Join(dir, "6_add_index_on_users_phone.go"))
+}
+
+func TestCreateMigrationGo(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	paths, err := CreateMigration(CreateOptions{Dir: dir, Name: "Create Users Table", Type: MigrationFileGo})
+	assert.NoError(t, err)
+
+	if assert.Equal(t, []string{filepath.Join(dir, "1_create_users_table.go")}, paths) {
+		content, readErr := os.ReadFile(paths[0])
+		assert.NoError(t, readErr)
+		assert.Contains(t, string(content), `Name:   "Create Users Table",`)
+		assert.Contains(t, string(content), "Number: 1,")
+
+		formatted, formatErr := format.Source(content)
+		assert.NoError(t, formatErr)
+		assert.Equal(t, string(formatted), string(content), "gofmt-ed")
+
+		parsed, parseErr := parser.ParseFile(token.NewFileSet(), paths[0], content, 0)
+		assert.NoError(t, parseErr)
+		assert.Equal(t, "migrations", parsed.Name.Name, "named after the directory")
+	}
+
+	dir = t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "doc.go"), []byte("package schema\n"), 0o600))
+
+	paths, err = CreateMigration(CreateOptions{Dir: dir, Name: "add phone", Type: MigrationFileGo})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(paths[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "package schema\n", "package of the other files")
+}
+
+func TestCreateMigrationErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name        string
+		opt         CreateOptions
+		expectedErr error
+	}{
+		{
+			name:        "empty name",
+			opt:         CreateOptions{Dir: dir, Name: " - "},
+			expectedErr: errMigrationNameCannotBeEmpty,
+		},
+		{
+			name:        "unknown type",
+			opt:         CreateOptions{Dir: dir, Name: "add email", Type: "yaml"},
+			expectedErr: errUnknownFileType,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := CreateMigration(testCase.opt)
+		assert.ErrorIs(t, err, testCase.expectedErr, testCase.name)
+	}
+
+	existing := filepath.Join(dir, "0001_add_email.up.sql")
+
+	assert.NoError(t, os.WriteFile(existing, []byte("SELECT 1"), 0o600))
+	assert.ErrorIs(t, writeNewFile(existing, nil), os.ErrExist, "existing file")
+
+	content, err := os.ReadFile(existing)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1", string(content), "not overwritten")
+}
@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MigrationState tells whether a migration is applied.
+type MigrationState string
+
+const (
+	// StateApplied is a known migration recorded as applied.
+	StateApplied MigrationState = "applied"
+	// StatePending is a known migration not applied yet, numbered above the latest applied one.
+	StatePending MigrationState = "pending"
+	// StateOutOfOrder is a known migration not applied yet, numbered below the latest applied one.
+	StateOutOfOrder MigrationState = "out-of-order"
+	// StateMissingFromCode is a migration recorded as applied that is not known.
+	StateMissingFromCode MigrationState = "missing-from-code"
+)
+
+// ChecksumState tells whether the checksum of an applied migration matches the known one.
+type ChecksumState string
+
+const (
+	// ChecksumStateValid is a recorded checksum matching the known one.
+	ChecksumStateValid ChecksumState = "valid"
+	// ChecksumStateChanged is a recorded checksum differing from the known one.
+	ChecksumStateChanged ChecksumState = "changed"
+	// ChecksumStateUnknown is a migration without a recorded or a known checksum to compare.
+	ChecksumStateUnknown ChecksumState = "unknown"
+)
+
+// MigrationStatus describes a known or applied migration.
+type MigrationStatus struct {
+	Number    uint           `json:"number"`
+	Name      string         `json:"name"`
+	State     MigrationState `json:"state"`
+	AppliedAt *time.Time     `json:"applied_at,omitempty"`
+	Checksum  ChecksumState  `json:"checksum"`
+	Dirty     bool           `json:"dirty"`
+
+	// Baselined is a migration recorded as applied by Migrate.Baseline, without running it.
+	Baselined bool `json:"baselined"`
+}
+
+// status lists every known or applied migration ordered by number, without changing anything in the database.
+func (m *migrationTask) status(ctx context.Context) ([]MigrationStatus, error) {
+	exists, err := m.repo.MigrationTableExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the migrations table: %w", err)
+	}
+
+	var applied []*migration
+
+	if exists {
+		applied, err = m.repo.GetAppliedMigrations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+	}
+
+	appliedMigrations := newAppliedSet(applied)
+	lastAppliedMigrationNumber := appliedMigrations.latest()
+	statuses := make([]MigrationStatus, 0, len(m.migrations)+len(applied))
+	known := make(map[uint]bool, len(m.migrations))
+
+	for _, knownMigration := range m.migrations {
+		known[knownMigration.Number] = true
+
+		appliedMigration, ok := appliedMigrations[knownMigration.Number]
+
+		switch {
+		case ok:
+			statuses = append(statuses, newAppliedStatus(appliedMigration, StateApplied, knownMigration.Checksum))
+		case knownMigration.Number < lastAppliedMigrationNumber:
+			statuses = append(statuses, newPendingStatus(knownMigration, StateOutOfOrder))
+		default:
+			statuses = append(statuses, newPendingStatus(knownMigration, StatePending))
+		}
+	}
+
+	for _, appliedMigration := range applied {
+		if !known[appliedMigration.Number] {
+			statuses = append(statuses, newAppliedStatus(appliedMigration, StateMissingFromCode, ""))
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Number < statuses[j].Number
+	})
+
+	return statuses, nil
+}
+
+func newAppliedStatus(applied *migration, state MigrationState, knownChecksum string) MigrationStatus {
+	appliedAt := applied.CreatedAt
+
+	checksum := ChecksumStateUnknown
+
+	if applied.Checksum != "" && knownChecksum != "" {
+		checksum = ChecksumStateValid
+
+		if applied.Checksum != knownChecksum {
+			checksum = ChecksumStateChanged
+		}
+	}
+
+	return MigrationStatus{
+		Number:    applied.Number,
+		Name:      applied.Name,
+		State:     state,
+		AppliedAt: &appliedAt,
+		Checksum:  checksum,
+		Dirty:     applied.Dirty,
+		Baselined: applied.Baselined,
+	}
+}
+
+func newPendingStatus(known *migration, state MigrationState) MigrationStatus {
+	return MigrationStatus{
+		Number:    known.Number,
+		Name:      known.Name,
+		State:     state,
+		AppliedAt: nil,
+		Checksum:  ChecksumStateUnknown,
+		Dirty:     false,
+		Baselined: false,
+	}
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Tx is the handle a migration runs with.
+//
+// Regular migrations run in a transaction, held by Tx.
+// Migrations with NoTransaction set run on a single connection instead, held by Conn while Tx is nil.
+// The Exec, Query and QueryRow methods run on whichever of the two is set.
+type Tx struct {
+	*sql.Tx
+
+	// Conn is the connection a migration with NoTransaction set runs on.
+	Conn *sql.Conn
+
+	// raw is the connection the migration runs on, with or without a transaction, see WithPgxConn.
+	raw *sql.Conn
+
+	ctx context.Context //nolint:containedctx // handed to migrations through Context
+}
+
+// Context returns the context the migration runs with. It is done once the migration is cancelled.
+func (tx Tx) Context() context.Context {
+	if tx.ctx == nil {
+		return context.Background()
+	}
+
+	return tx.ctx
+}
+
+// ExecContext executes a query without returning any rows.
+func (tx Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx.Tx != nil {
+		return tx.Tx.ExecContext(ctx, query, args...) //nolint:wrapcheck // passed through as is
+	}
+
+	return tx.Conn.ExecContext(ctx, query, args...) //nolint:wrapcheck // passed through as is
+}
+
+// Exec executes a query without returning any rows.
+func (tx Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(tx.Context(), query, args...)
+}
+
+// QueryContext executes a query that returns rows.
+func (tx Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx.Tx != nil {
+		return tx.Tx.QueryContext(ctx, query, args...) //nolint:wrapcheck // passed through as is
+	}
+
+	return tx.Conn.QueryContext(ctx, query, args...) //nolint:wrapcheck // passed through as is
+}
+
+// Query executes a query that returns rows.
+func (tx Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(tx.Context(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+func (tx Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if tx.Tx != nil {
+		return tx.Tx.QueryRowContext(ctx, query, args...)
+	}
+
+	return tx.Conn.QueryRowContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+func (tx Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(tx.Context(), query, args...)
+}
+
+// ManualInterventionError is returned when a migration running without a transaction fails.
+// Its changes may be partially applied and are not rolled back, so it has to be looked into by hand.
+type ManualInterventionError struct {
+	Number uint
+	Name   string
+	Err    error
+}
+
+func (e *ManualInterventionError) Error() string {
+	return fmt.Sprintf("migration %d (%s) ran without a transaction and failed, "+
+		"it may be partially applied and needs manual attention: %v", e.Number, e.Name, e.Err)
+}
+
+func (e *ManualInterventionError) Unwrap() error {
+	return e.Err
+}
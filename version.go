@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimestampVersionLayout is the time layout of timestamp versions, e.g. 20261017093000.
+// Timestamp versions sort after sequential ones, so a sequence can be continued with timestamps.
+const TimestampVersionLayout = "20060102150405"
+
+var errVersionOverflow = errors.New("version does not fit in uint, timestamp versions require a 64-bit platform")
+
+// TimestampVersion returns the timestamp version of t in UTC, to number migrations by their creation time
+// instead of sequentially, so that migrations created in parallel do not collide.
+// It fails on 32-bit platforms, where timestamp versions overflow uint.
+func TimestampVersion(t time.Time) (uint, error) {
+	formatted := t.UTC().Format(TimestampVersionLayout)
+
+	version, err := strconv.ParseUint(formatted, 10, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errVersionOverflow, formatted)
+	}
+
+	return uint(version), nil
+}
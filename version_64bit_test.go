@@ -0,0 +1,88 @@
+//go:build !(386 || arm || mips || mipsle)
+
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests below cover timestamp versions and other numbers that only fit in a 64-bit uint.
+
+func TestNewLargeNumbers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		migrations  []*Migration
+		expectedErr error
+	}{
+		{
+			name: "number overflow",
+			migrations: []*Migration{
+				{Name: "Test Migration", Number: math.MaxInt64 + 1, Up: func(tx Tx) error { return nil }, Down: nil},
+			},
+			expectedErr: errMigrationNumberOverflow,
+		},
+		{
+			name: "timestamp numbers",
+			migrations: []*Migration{
+				{Name: "Test Migration", Number: 1, Up: func(tx Tx) error { return nil }, Down: nil},
+				{Name: "Test Migration 2", Number: 20261017093000, Up: func(tx Tx) error { return nil }, Down: nil},
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := NewWithRegistry(&Registry{migrations: testCase.migrations}, Options{})
+		assert.ErrorIs(t, err, testCase.expectedErr, testCase.name)
+	}
+}
+
+func TestLoadMigrationsFSTimestamp(t *testing.T) {
+	t.Parallel()
+
+	loaded, err := LoadMigrationsFS(fstest.MapFS{
+		"migrations/0004_add_phone_for_users.up.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN phone TEXT")},
+		"migrations/20261017093000_add_nickname.up.sql": {Data: []byte("ALTER TABLE users ADD COLUMN nickname TEXT")},
+	}, "migrations")
+	assert.NoError(t, err)
+
+	if assert.Len(t, loaded, 2) {
+		assert.Equal(t, uint(20261017093000), loaded[1].Number, "timestamp versions sort after sequential ones")
+	}
+
+	assert.NoError(t, validateMigrations(append(prepareMigrations(), loaded...)), "Mixed With Go Migrations")
+}
+
+func TestCreateMigrationTimestamp(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	_, err := CreateMigration(CreateOptions{Dir: dir, Name: "create users"})
+	assert.NoError(t, err)
+
+	paths, err := CreateMigration(CreateOptions{
+		Dir:       dir,
+		Name:      "add nickname",
+		Timestamp: true,
+		Now:       time.Date(2026, time.October, 17, 9, 30, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20261017093000_add_nickname.up.sql"), paths[0])
+
+	paths, err = CreateMigration(CreateOptions{Dir: dir, Name: "add email"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20261017093001_add_email.up.sql"), paths[0], "continues after timestamps")
+
+	_, err = os.Stat(filepath.Join(dir, "0001_create_users.up.sql"))
+	assert.NoError(t, err, "sequential before timestamps")
+}
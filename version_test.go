@@ -0,0 +1,29 @@
+package migrate //nolint:testpackage // allow direct tests
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampVersion(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2026, time.October, 17, 11, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	version, err := TimestampVersion(createdAt)
+	if strconv.IntSize < 64 {
+		assert.ErrorIs(t, err, errVersionOverflow, "overflows a 32-bit uint")
+
+		return
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20261017093000), uint64(version), "in UTC")
+
+	next, err := TimestampVersion(createdAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Less(t, version, next)
+}